@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// difficulty is how hard a question is, set with the optional difficulty field in the quiz file.
+type difficulty int
+
+const (
+	unrated difficulty = iota
+	easy
+	medium
+	hard
+)
+
+func (d difficulty) String() string {
+	switch d {
+	case easy:
+		return "easy"
+	case medium:
+		return "medium"
+	case hard:
+		return "hard"
+	default:
+		return ""
+	}
+}
+
+// parseDifficulty parses the difficulty field of a question. An empty field leaves the question unrated.
+func parseDifficulty(s string) (difficulty, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return unrated, nil
+	case "easy":
+		return easy, nil
+	case "medium":
+		return medium, nil
+	case "hard":
+		return hard, nil
+	default:
+		return unrated, fmt.Errorf("unknown difficulty '%s', expected one of: easy, medium, hard", s)
+	}
+}
+
+// level returns the difficulty used when choosing and scoring the question. Unrated questions count as medium.
+func (d difficulty) level() difficulty {
+	if d == unrated {
+		return medium
+	}
+
+	return d
+}
+
+// points is how much a correct answer is worth, so harder questions are weighted more: easy questions are worth 1
+// point, medium (and unrated) questions 2 and hard questions 3.
+func (d difficulty) points() int {
+	return int(d.level())
+}
+
+// hasTag reports whether the question is tagged with any of tags, ignoring case.
+func (q quizQuestion) hasTag(tags []string) bool {
+	for _, want := range tags {
+		for _, tag := range q.tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// filterByTags returns the questions which have at least one of tags, or all the questions when tags is empty.
+func filterByTags(questions []quizQuestion, tags []string) []quizQuestion {
+	if len(tags) == 0 {
+		return questions
+	}
+
+	filtered := make([]quizQuestion, 0)
+	for _, q := range questions {
+		if q.hasTag(tags) {
+			filtered = append(filtered, q)
+		}
+	}
+
+	return filtered
+}
+
+// adaptiveOrder chooses the next question based on how the previous one was answered. It starts at medium difficulty
+// and moves up a level after each correct answer, and down a level after each wrong or timed out answer.
+type adaptiveOrder struct {
+	level difficulty
+}
+
+// newAdaptiveOrder returns an adaptiveOrder at the level reached after the answers given so far, so that a resumed
+// quiz carries on at the same difficulty.
+func newAdaptiveOrder(records []answerRecord) *adaptiveOrder {
+	a := &adaptiveOrder{level: medium}
+	for _, r := range records {
+		a.Record(r.outcome)
+	}
+
+	return a
+}
+
+// Record moves the level up or down after a question has been answered.
+func (a *adaptiveOrder) Record(o outcome) {
+	if o == answeredCorrectly {
+		a.level = min(a.level+1, hard)
+		return
+	}
+	a.level = max(a.level-1, easy)
+}
+
+// Next returns the index of the question in remaining which is closest to the current level. Ties go to the easier
+// question, and otherwise questions are taken in order so that -random still shuffles questions of the same level.
+func (a *adaptiveOrder) Next(remaining []quizQuestion) int {
+	best := 0
+	for i, q := range remaining {
+		d, b := q.difficulty.level(), remaining[best].difficulty.level()
+		if distance(d, a.level) < distance(b, a.level) || (distance(d, a.level) == distance(b, a.level) && d < b) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+func distance(a, b difficulty) int {
+	if a > b {
+		return int(a - b)
+	}
+
+	return int(b - a)
+}
+
+// topicResult is the score for a single topic tag within a quizReport.
+type topicResult struct {
+	Topic   string `json:"topic"`
+	Correct int    `json:"correct"`
+	Total   int    `json:"total"`
+}
+
+// topicBreakdown totals the results of the report rows for each topic tag, sorted by topic. Questions can have
+// several tags, so they can count towards more than one topic. Nil is returned when no questions are tagged.
+func topicBreakdown(rows []reportRow) []topicResult {
+	byTopic := make(map[string]*topicResult)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			key := strings.ToLower(tag)
+			if byTopic[key] == nil {
+				byTopic[key] = &topicResult{Topic: tag}
+			}
+			byTopic[key].Total++
+			if row.Result == answeredCorrectly.String() {
+				byTopic[key].Correct++
+			}
+		}
+	}
+	if len(byTopic) == 0 {
+		return nil
+	}
+
+	topics := make([]topicResult, 0, len(byTopic))
+	for _, t := range byTopic {
+		topics = append(topics, *t)
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		return strings.ToLower(topics[i].Topic) < strings.ToLower(topics[j].Topic)
+	})
+
+	return topics
+}
+
+// splitTags splits the comma separated -tags flag into its trimmed, non-empty tags.
+func splitTags(flag string) []string {
+	tags := make([]string, 0)
+	for _, t := range strings.Split(flag, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_adaptiveOrder(t *testing.T) {
+	questions := []quizQuestion{
+		{question: "2+2", difficulty: easy},
+		{question: "3+3", difficulty: easy},
+		{question: "12*12", difficulty: hard},
+		{question: "5*5", difficulty: medium},
+		{question: "7*8"},
+	}
+
+	order := newAdaptiveOrder(nil)
+	assert.Equal(t, 3, order.Next(questions), "expected to start with a medium question")
+
+	order.Record(answeredCorrectly)
+	assert.Equal(t, 2, order.Next(questions), "expected a harder question after a correct answer")
+
+	order.Record(answeredCorrectly)
+	assert.Equal(t, hard, order.level, "expected the level to stop at hard")
+
+	order.Record(timedOut)
+	order.Record(answeredIncorrectly)
+	assert.Equal(t, 0, order.Next(questions), "expected an easier question after wrong answers")
+	assert.Equal(t, 1, order.Next(questions[2:]), "expected the closest question when none are at the current level")
+
+	order.Record(answeredCorrectly)
+	assert.Equal(t, 1, order.Next([]quizQuestion{questions[2], questions[0]}), "expected the easier question when two are equally close")
+
+	resumed := newAdaptiveOrder([]answerRecord{{outcome: answeredCorrectly}, {outcome: answeredCorrectly}})
+	assert.Equal(t, hard, resumed.level, "expected a resumed quiz to carry on at the same level")
+}
+
+func Test_filterByTags(t *testing.T) {
+	questions := []quizQuestion{
+		{question: "2+2", tags: []string{"arithmetic"}},
+		{question: "capital of France?", tags: []string{"Geography", "capitals"}},
+		{question: "largest ocean?"},
+	}
+
+	assert.Equal(t, questions, filterByTags(questions, nil), "expected every question without any tags")
+	assert.Equal(t, []string{"capital of France?"}, questionOrder(filterByTags(questions, splitTags("geography, history"))))
+	assert.Empty(t, filterByTags(questions, []string{"history"}))
+}
+
+func Test_weightedReport(t *testing.T) {
+	questions := []quizQuestion{
+		{question: "2+2", answer: "4", difficulty: easy, tags: []string{"arithmetic"}},
+		{question: "12*12", answer: "144", difficulty: hard, tags: []string{"arithmetic"}},
+		{question: "capital of France?", answer: "Paris", tags: []string{"geography"}},
+	}
+	records := []answerRecord{
+		{number: 0, question: questions[0], given: "5", outcome: answeredIncorrectly},
+		{number: 1, question: questions[1], given: "144", outcome: answeredCorrectly},
+		{number: 2, question: questions[2], given: "paris", outcome: answeredCorrectly},
+	}
+
+	report := buildReport("tagged.csv", time.Time{}, questions, records)
+	assert.Equal(t, 5, report.Score, "expected the hard and unrated questions to be worth 3 and 2 points")
+	assert.Equal(t, 6, report.MaxScore)
+	assert.Equal(t, []topicResult{{Topic: "arithmetic", Correct: 1, Total: 2}, {Topic: "geography", Correct: 1, Total: 1}}, report.Topics)
+	assert.True(t, report.rated())
+}
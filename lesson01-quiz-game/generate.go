@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxGeneratedQuestions is the most questions which can be generated, as every question is held in memory
+const maxGeneratedQuestions = 10000
+
+// generatorSpec describes a bank of arithmetic questions to generate, parsed from the -generate flag.
+type generatorSpec struct {
+	count int
+	ops   []rune
+	min   int
+	max   int
+	// integer only generates division questions with a whole number answer
+	integer bool
+}
+
+// parseGeneratorSpec parses a spec such as 'count=20,ops=+-*/,operands=1-12,integer'. Every setting is optional,
+// with defaults of 10 questions, addition only and operands from 1 to 10.
+func parseGeneratorSpec(spec string) (generatorSpec, error) {
+	s := generatorSpec{count: 10, ops: []rune{'+'}, min: 1, max: 10}
+
+	for _, setting := range strings.Split(spec, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(setting), "=")
+
+		switch strings.ToLower(name) {
+		case "":
+			continue
+		case "count":
+			count, err := strconv.Atoi(value)
+			if err != nil || count <= 0 {
+				return s, fmt.Errorf("invalid count '%s', expected a positive whole number", value)
+			}
+			if count > maxGeneratedQuestions {
+				return s, fmt.Errorf("count %d is too large, at most %d questions can be generated", count, maxGeneratedQuestions)
+			}
+			s.count = count
+		case "ops":
+			s.ops = nil
+			for _, op := range value {
+				// x is accepted for multiplication as it is easier to type in a shell than *
+				if op == 'x' {
+					op = '*'
+				}
+				if !strings.ContainsRune("+-*/", op) {
+					return s, fmt.Errorf("invalid operator '%c', expected any of: + - * /", op)
+				}
+				s.ops = append(s.ops, op)
+			}
+			if len(s.ops) == 0 {
+				return s, fmt.Errorf("ops must contain at least one of: + - * /")
+			}
+		case "operands":
+			lo, hi, err := parseRange(value)
+			if err != nil {
+				return s, err
+			}
+			s.min, s.max = lo, hi
+		case "integer":
+			s.integer = true
+		default:
+			return s, fmt.Errorf("unknown setting '%s' in generate spec, expected count, ops, operands or integer", name)
+		}
+	}
+
+	if strings.ContainsRune(string(s.ops), '/') && s.min == 0 && s.max == 0 {
+		return s, fmt.Errorf("operands must include a number other than 0 to generate division questions")
+	}
+
+	return s, nil
+}
+
+// rangePattern matches an operand range such as '1-12' or '-10--1'.
+var rangePattern = regexp.MustCompile(`^(-?\d+)-(-?\d+)$`)
+
+// parseRange parses an operand range into its lowest and highest values.
+func parseRange(value string) (int, int, error) {
+	m := rangePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, 0, fmt.Errorf("invalid operands '%s', expected a range such as 1-12", value)
+	}
+
+	lo, _ := strconv.Atoi(m[1])
+	hi, _ := strconv.Atoi(m[2])
+	if hi < lo {
+		return 0, 0, fmt.Errorf("invalid operands '%s', the lowest number must come first", value)
+	}
+
+	return lo, hi, nil
+}
+
+// generateQuestions returns the questions described by spec. The same spec and seed always give the same questions,
+// so a generated quiz can be repeated or shared.
+func generateQuestions(spec generatorSpec, seed int64) []quizQuestion {
+	rng := rand.New(rand.NewSource(seed))
+
+	questions := make([]quizQuestion, 0, spec.count)
+	for i := 0; i < spec.count; i++ {
+		op := spec.ops[rng.Intn(len(spec.ops))]
+		questions = append(questions, spec.question(rng, op))
+	}
+
+	return questions
+}
+
+// question generates a single question using op.
+func (s generatorSpec) question(rng *rand.Rand, op rune) quizQuestion {
+	a, b := s.operand(rng), s.operand(rng)
+
+	var answer float64
+	switch op {
+	case '+':
+		answer = float64(a + b)
+	case '-':
+		answer = float64(a - b)
+	case '*':
+		answer = float64(a * b)
+	case '/':
+		for b == 0 {
+			b = s.operand(rng)
+		}
+		if s.integer {
+			a = s.multipleOf(rng, b)
+		}
+		answer = float64(a) / float64(b)
+	}
+
+	q := quizQuestion{question: fmt.Sprintf("%d%c%d", a, op, b), matcher: numericMatcher{}}
+	if answer == math.Trunc(answer) {
+		q.answer = strconv.FormatFloat(answer, 'f', -1, 64)
+		return q
+	}
+
+	// Answers which aren't whole numbers are asked to 2 decimal places, rounding halves up
+	q.question += " (to 2 decimal places)"
+	q.answer = strconv.FormatFloat(math.Round(answer*100)/100, 'f', 2, 64)
+	q.matcher = numericMatcher{tolerance: 0.005}
+
+	return q
+}
+
+// operand returns a random number from the operand range.
+func (s generatorSpec) operand(rng *rand.Rand) int {
+	return s.min + rng.Intn(s.max-s.min+1)
+}
+
+// multipleOf returns a random multiple of b from the operand range, so that dividing it by b gives a whole number.
+// When there are none in the range (e.g. dividing by 12 with operands 1-10) b times another operand is used instead.
+func (s generatorSpec) multipleOf(rng *rand.Rand, b int) int {
+	lo := int(math.Ceil(float64(s.min) / float64(b)))
+	hi := int(math.Floor(float64(s.max) / float64(b)))
+	if b < 0 {
+		lo, hi = int(math.Ceil(float64(s.max)/float64(b))), int(math.Floor(float64(s.min)/float64(b)))
+	}
+	if lo > hi {
+		return b * s.operand(rng)
+	}
+
+	return b * (lo + rng.Intn(hi-lo+1))
+}
+
+// generatedQuizName describes a generated quiz in reports and the quiz history.
+func generatedQuizName(spec string, seed int64) string {
+	return fmt.Sprintf("generated:%s (seed %d)", spec, seed)
+}
+
+// hashGeneratedQuiz identifies a generated quiz in the quiz history, in the same way hashQuizFile does for a quiz
+// file. Quizzes generated from the same spec and seed have the same questions, so they share a leaderboard.
+func hashGeneratedQuiz(spec string, seed int64) string {
+	h := sha256.Sum256([]byte(generatedQuizName(spec, seed)))
+
+	return hex.EncodeToString(h[:])
+}
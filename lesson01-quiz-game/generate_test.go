@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseGeneratorSpec(t *testing.T) {
+	spec, err := parseGeneratorSpec("count=20, ops=+-x/, operands=1-12, integer")
+	assert.NoError(t, err)
+	assert.Equal(t, generatorSpec{count: 20, ops: []rune{'+', '-', '*', '/'}, min: 1, max: 12, integer: true}, spec)
+
+	spec, err = parseGeneratorSpec("operands=-10--1")
+	assert.NoError(t, err)
+	assert.Equal(t, generatorSpec{count: 10, ops: []rune{'+'}, min: -10, max: -1}, spec, "expected the defaults for any settings which aren't given")
+
+	for _, invalid := range []string{"count=0", "count=10001", "count=99999999999", "ops=%", "ops=", "operands=12-1", "operands=1to12", "ops=/,operands=0-0", "colour=red"} {
+		_, err = parseGeneratorSpec(invalid)
+		assert.Error(t, err, "expected an error for '%s'", invalid)
+	}
+}
+
+func Test_generateQuestions(t *testing.T) {
+	spec, err := parseGeneratorSpec("count=50,ops=+-*/,operands=1-12,integer")
+	assert.NoError(t, err)
+
+	questions := generateQuestions(spec, 42)
+	assert.Equal(t, 50, len(questions))
+	assert.Equal(t, questions, generateQuestions(spec, 42), "expected the same questions from the same seed")
+	assert.NotEqual(t, questions, generateQuestions(spec, 43), "expected different questions from a different seed")
+
+	for _, q := range questions {
+		assert.NotContains(t, q.question, "decimal places", "expected only whole number answers with integer")
+		assert.NotContains(t, q.answer, ".", "expected only whole number answers with integer")
+	}
+}
+
+func Test_generatedQuestionAnswers(t *testing.T) {
+	questions := generateQuestions(generatorSpec{count: 200, ops: []rune{'/'}, min: 5, max: 8}, 1)
+
+	answers := make(map[string]string)
+	for _, q := range questions {
+		assert.True(t, q.IsCorrect(q.answer), "expected the generated answer to '%s' to be correct", q.question)
+		answers[q.question] = q.answer
+	}
+	assert.Equal(t, "0.63", answers["5/8 (to 2 decimal places)"], "expected halves to be rounded up")
+	assert.Equal(t, "1", answers["7/7"])
+
+	rng := rand.New(rand.NewSource(1))
+	q := generatorSpec{min: 3, max: 3}.question(rng, '-')
+	assert.Equal(t, "3-3", q.question)
+	assert.Equal(t, "0", q.answer)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// configDirName is the directory within the user's config dir where the quiz keeps its state
+	configDirName   = "quiz-game"
+	historyFileName = "history.jsonl"
+
+	// leaderboardSize is the number of players shown for each quiz file
+	leaderboardSize = 10
+)
+
+// historyEntry is a single finished quiz run, stored as one line of JSON in the history file. Score and MaxScore are the
+// weighted points, which are only recorded when the quiz has difficulties.
+type historyEntry struct {
+	Player     string    `json:"player"`
+	QuizFile   string    `json:"quiz_file"`
+	QuizHash   string    `json:"quiz_hash"`
+	Correct    int       `json:"correct"`
+	Total      int       `json:"total"`
+	Score      int       `json:"score,omitempty"`
+	MaxScore   int       `json:"max_score,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// duration returns how long the run took.
+func (e historyEntry) duration() time.Duration {
+	return time.Duration(e.DurationMs) * time.Millisecond
+}
+
+// fraction returns the fraction of the points scored when the quiz is rated, otherwise of the questions which were
+// answered correctly.
+func (e historyEntry) fraction() float64 {
+	if e.MaxScore > 0 {
+		return float64(e.Score) / float64(e.MaxScore)
+	}
+	if e.Total == 0 {
+		return 0
+	}
+
+	return float64(e.Correct) / float64(e.Total)
+}
+
+// betterThan reports whether e is a better score than other. The highest fraction of points or correct answers wins, so
+// runs which asked a different number of questions can be compared, then the fastest run.
+func (e historyEntry) betterThan(other historyEntry) bool {
+	if e.fraction() != other.fraction() {
+		return e.fraction() > other.fraction()
+	}
+
+	return e.DurationMs < other.DurationMs
+}
+
+// result returns the number of correct answers, followed by the points scored when the quiz is rated.
+func (e historyEntry) result() string {
+	if e.MaxScore > 0 {
+		return fmt.Sprintf("%d/%d (%d/%d points)", e.Correct, e.Total, e.Score, e.MaxScore)
+	}
+
+	return fmt.Sprintf("%d/%d", e.Correct, e.Total)
+}
+
+// historyStore persists quiz runs to a JSON-lines file, so that runs are only ever appended.
+type historyStore struct {
+	path string
+}
+
+// newHistoryStore returns a store using path, or the history file within the user's config dir when path is empty.
+func newHistoryStore(path string) (historyStore, error) {
+	if path != "" {
+		return historyStore{path: path}, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return historyStore{}, fmt.Errorf("finding the user config dir: %v", err)
+	}
+
+	return historyStore{path: filepath.Join(configDir, configDirName, historyFileName)}, nil
+}
+
+// Append adds a finished run to the end of the history file, creating it if needed.
+func (s historyStore) Append(e historyEntry) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating history dir: %v", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening history file '%s': %v", s.path, err)
+	}
+	defer f.Close()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding history entry: %v", err)
+	}
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("writing history file '%s': %v", s.path, err)
+	}
+
+	return f.Close()
+}
+
+// Load returns every run in the history file, in the order they were recorded.
+// A missing file is treated as an empty history and corrupt lines are logged and skipped.
+func (s historyStore) Load() ([]historyEntry, error) {
+	entries := make([]historyEntry, 0)
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening history file '%s': %v", s.path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			log.Printf("Skipping %s %s", s.path, skippedRow{line: line, reason: err.Error()})
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history file '%s': %v", s.path, err)
+	}
+
+	return entries, nil
+}
+
+// hashQuizFile returns the SHA-256 of the quiz file, so that runs are grouped by the questions that were asked
+// rather than by the path the file happened to be loaded from.
+func hashQuizFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening quiz file '%s': %v", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing quiz file '%s': %v", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// leaderboard returns each player's best run for every quiz file, keyed by the quiz hash and sorted best first.
+func leaderboard(entries []historyEntry) map[string][]historyEntry {
+	best := make(map[string]map[string]historyEntry)
+	for _, e := range entries {
+		if best[e.QuizHash] == nil {
+			best[e.QuizHash] = make(map[string]historyEntry)
+		}
+		if current, ok := best[e.QuizHash][e.Player]; !ok || e.betterThan(current) {
+			best[e.QuizHash][e.Player] = e
+		}
+	}
+
+	boards := make(map[string][]historyEntry)
+	for hash, players := range best {
+		for _, e := range players {
+			boards[hash] = append(boards[hash], e)
+		}
+		sort.Slice(boards[hash], func(i, j int) bool {
+			a, b := boards[hash][i], boards[hash][j]
+			// Ties go to whoever got the score first
+			if !a.betterThan(b) && !b.betterThan(a) {
+				return a.FinishedAt.Before(b.FinishedAt)
+			}
+			return a.betterThan(b)
+		})
+	}
+
+	return boards
+}
+
+// printHistory prints every recorded run, most recent first. When player is set only their runs are shown.
+func printHistory(entries []historyEntry, player string) {
+	runs := make([]historyEntry, 0, len(entries))
+	for _, e := range entries {
+		if player == "" || e.Player == player {
+			runs = append(runs, e)
+		}
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("There are no recorded quiz runs")
+		return
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].FinishedAt.After(runs[j].FinishedAt)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Finished\tPlayer\tQuiz\tScore\tDuration")
+	for _, e := range runs {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.FinishedAt.Format(time.RFC822), e.Player, e.QuizFile, e.result(), e.duration())
+	}
+	_ = w.Flush()
+}
+
+// printLeaderboard prints the best score of each player for every quiz file.
+func printLeaderboard(entries []historyEntry) {
+	boards := leaderboard(entries)
+	if len(boards) == 0 {
+		fmt.Println("There are no recorded quiz runs")
+		return
+	}
+
+	// Name each quiz by the path it was most recently run from, as the same file may have been moved around
+	names := make(map[string]string)
+	for _, e := range entries {
+		names[e.QuizHash] = e.QuizFile
+	}
+
+	hashes := make([]string, 0, len(boards))
+	for hash := range boards {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return names[hashes[i]] < names[hashes[j]]
+	})
+
+	for _, hash := range hashes {
+		fmt.Printf("\n%s (%.12s)\n", names[hash], hash)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "Rank\tPlayer\tScore\tDuration\tFinished")
+		for i, e := range boards[hash] {
+			if i == leaderboardSize {
+				break
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, e.Player, e.result(), e.duration(), e.FinishedAt.Format(time.RFC822))
+		}
+		_ = w.Flush()
+	}
+}
+
+// defaultPlayer returns the name of the logged-in user, to be used when -player is not set.
+func defaultPlayer() string {
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+
+	return "anonymous"
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_historyStore(t *testing.T) {
+	store, err := newHistoryStore(filepath.Join(t.TempDir(), "nested", "history.jsonl"))
+	assert.NoError(t, err)
+
+	entries, err := store.Load()
+	assert.NoError(t, err, "expected a missing history file to be treated as empty")
+	assert.Equal(t, 0, len(entries))
+
+	first := historyEntry{Player: "alice", QuizFile: "problems.csv", QuizHash: "abc", Correct: 2, Total: 3, DurationMs: 1000, FinishedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	second := historyEntry{Player: "bob", QuizFile: "problems.csv", QuizHash: "abc", Correct: 3, Total: 3, DurationMs: 2000, FinishedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+	assert.NoError(t, store.Append(first))
+	assert.NoError(t, store.Append(second))
+
+	// Corrupt lines should be skipped rather than losing the whole history
+	f, err := os.OpenFile(store.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, _ = f.WriteString("not json\n")
+	_ = f.Close()
+
+	entries, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []historyEntry{first, second}, entries)
+}
+
+func Test_leaderboard(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	entries := []historyEntry{
+		{Player: "alice", QuizHash: "quiz-1", Correct: 2, Total: 3, DurationMs: 1000, FinishedAt: day(1)},
+		{Player: "alice", QuizHash: "quiz-1", Correct: 3, Total: 3, DurationMs: 9000, FinishedAt: day(2)},
+		{Player: "bob", QuizHash: "quiz-1", Correct: 3, Total: 3, DurationMs: 5000, FinishedAt: day(3)},
+		{Player: "carol", QuizHash: "quiz-1", Correct: 1, Total: 3, DurationMs: 500, FinishedAt: day(4)},
+		{Player: "dave", QuizHash: "quiz-1", Correct: 2, Total: 6, DurationMs: 100, FinishedAt: day(4)},
+		{Player: "erin", QuizHash: "quiz-1", Correct: 2, Total: 2, DurationMs: 100, FinishedAt: day(6)},
+		{Player: "alice", QuizHash: "quiz-2", Correct: 1, Total: 1, DurationMs: 100, FinishedAt: day(5)},
+	}
+
+	boards := leaderboard(entries)
+	assert.Equal(t, 2, len(boards))
+
+	players := make([]string, 0)
+	for _, e := range boards["quiz-1"] {
+		players = append(players, e.Player)
+	}
+	// erin only answered 2 questions, but got every one of them right
+	assert.Equal(t, []string{"erin", "bob", "alice", "dave", "carol"}, players, "expected the fastest perfect score first")
+	assert.Equal(t, int64(9000), boards["quiz-1"][2].DurationMs, "expected alice's best score rather than her fastest run")
+}
+
+func Test_leaderboardRated(t *testing.T) {
+	entries := []historyEntry{
+		{Player: "alice", QuizHash: "quiz", Correct: 3, Total: 4, Score: 3, MaxScore: 9, DurationMs: 1000},
+		{Player: "bob", QuizHash: "quiz", Correct: 2, Total: 4, Score: 6, MaxScore: 9, DurationMs: 2000},
+	}
+
+	board := leaderboard(entries)["quiz"]
+	assert.Equal(t, 2, len(board))
+	assert.Equal(t, "bob", board[0].Player, "expected the hard questions bob answered to outrank alice's easy ones")
+	assert.Equal(t, "2/4 (6/9 points)", board[0].result())
+	assert.Equal(t, "3/4", historyEntry{Correct: 3, Total: 4}.result())
+}
+
+func Test_hashQuizFile(t *testing.T) {
+	a, err := hashQuizFile("./testdata/valid.csv")
+	assert.NoError(t, err)
+	b, err := hashQuizFile("./testdata/bad-record.csv")
+	assert.NoError(t, err)
+
+	assert.Len(t, a, 64)
+	assert.NotEqual(t, a, b)
+
+	_, err = hashQuizFile("./testdata/missing.csv")
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// lintProblem is a single problem found in a quiz file. A line of 0 means the problem applies to the whole file.
+type lintProblem struct {
+	path    string
+	line    int
+	message string
+}
+
+func (p lintProblem) String() string {
+	if p.line == 0 {
+		return fmt.Sprintf("%s: %s", p.path, p.message)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", p.path, p.line, p.message)
+}
+
+// lintQuizFile checks a quiz file for any rows which can't be loaded, invalid UTF-8, and questions which are repeated
+// (either as duplicates or with conflicting answers). Problems are returned in line order.
+func lintQuizFile(path, format string) []lintProblem {
+	problems := make([]lintProblem, 0)
+
+	// Problems are reported against the URL of a remote quiz file, rather than its cached copy
+	local, err := resolveQuizFile(path, "")
+	if err != nil {
+		return append(problems, lintProblem{path: path, message: err.Error()})
+	}
+
+	b, err := os.ReadFile(local)
+	if err != nil {
+		return append(problems, lintProblem{path: path, message: fmt.Sprintf("unable to read file: %v", err)})
+	}
+	problems = append(problems, lintEncoding(path, b)...)
+
+	source, err := sourceFor(local, format)
+	if err != nil {
+		return append(problems, lintProblem{path: path, message: err.Error()})
+	}
+
+	questions, skipped, err := source.Load(bytes.NewReader(b))
+	if err != nil {
+		return append(problems, lintProblem{path: path, message: err.Error()})
+	}
+
+	for _, s := range skipped {
+		problems = append(problems, lintProblem{path: path, line: s.line, message: s.reason})
+	}
+	problems = append(problems, lintDuplicates(path, questions)...)
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].line < problems[j].line
+	})
+
+	return problems
+}
+
+// lintEncoding reports a byte order mark (which hides the first column name from the header) and any lines which
+// are not valid UTF-8.
+func lintEncoding(path string, b []byte) []lintProblem {
+	problems := make([]lintProblem, 0)
+
+	if bytes.HasPrefix(b, []byte("\xef\xbb\xbf")) {
+		problems = append(problems, lintProblem{path: path, line: 1, message: "file starts with a UTF-8 byte order mark"})
+	}
+
+	for i, line := range bytes.Split(b, []byte("\n")) {
+		if !utf8.Valid(line) {
+			problems = append(problems, lintProblem{path: path, line: i + 1, message: "line is not valid UTF-8"})
+		}
+	}
+
+	return problems
+}
+
+// lintDuplicates reports questions which appear more than once. Questions are compared ignoring case and
+// whitespace, and a repeat with a different set of accepted answers is reported as a conflict.
+func lintDuplicates(path string, questions []quizQuestion) []lintProblem {
+	problems := make([]lintProblem, 0)
+	seen := make(map[string]quizQuestion)
+
+	for _, q := range questions {
+		key := normaliseAnswer(q.question)
+
+		first, ok := seen[key]
+		if !ok {
+			seen[key] = q
+			continue
+		}
+
+		if acceptedAnswersKey(first) == acceptedAnswersKey(q) {
+			problems = append(problems, lintProblem{path: path, line: q.line, message: fmt.Sprintf("duplicate question '%s' (first defined on line %d)", q.question, first.line)})
+			continue
+		}
+		problems = append(problems, lintProblem{path: path, line: q.line, message: fmt.Sprintf("conflicting answer '%s' for question '%s' (line %d has answer '%s')", q.answer, q.question, first.line, first.answer)})
+	}
+
+	return problems
+}
+
+// acceptedAnswersKey returns the accepted answers of a question in a form which can be compared.
+func acceptedAnswersKey(q quizQuestion) string {
+	answers := make([]string, 0)
+	for _, a := range q.acceptedAnswers() {
+		answers = append(answers, normaliseAnswer(a))
+	}
+	sort.Strings(answers)
+
+	return strings.Join(answers, "\x00")
+}
+
+// lintFiles lints every file and prints the problems, returning an error if any problems were found.
+func lintFiles(paths []string, format string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("usage: lint <quiz file> [quiz file...]")
+	}
+
+	count := 0
+	for _, path := range paths {
+		problems := lintQuizFile(path, format)
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		count += len(problems)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("found %d problem(s) in %d quiz file(s)", count, len(paths))
+	}
+	fmt.Printf("No problems found in %d quiz file(s)\n", len(paths))
+
+	return nil
+}
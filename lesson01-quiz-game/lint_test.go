@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lintQuizFile(t *testing.T) {
+	problems := lintQuizFile("./testdata/lint.csv", "")
+
+	messages := make([]string, 0)
+	for _, p := range problems {
+		messages = append(messages, p.String())
+	}
+
+	assert.Equal(t, []string{
+		"./testdata/lint.csv:3: duplicate question '1+1 ' (first defined on line 2)",
+		"./testdata/lint.csv:4: conflicting answer ' 3 ' for question '1+1' (line 2 has answer '2')",
+		"./testdata/lint.csv:5: empty answer",
+		"./testdata/lint.csv:6: line is not valid UTF-8",
+		"./testdata/lint.csv:7: expected 2 fields but found 3",
+	}, messages)
+}
+
+func Test_lintQuizFileValid(t *testing.T) {
+	for _, path := range []string{"./testdata/valid.csv", "./testdata/valid.json", "./testdata/valid.yaml", "./testdata/valid.md"} {
+		assert.Empty(t, lintQuizFile(path, ""), "expected no problems in %s", path)
+	}
+}
+
+func Test_lintFiles(t *testing.T) {
+	assert.Error(t, lintFiles([]string{"./testdata/lint.csv"}, ""), "expected an error so that lint exits non-zero")
+	assert.Error(t, lintFiles([]string{"./testdata/missing.csv"}, ""))
+	assert.Error(t, lintFiles(nil, ""), "expected an error when no files are given")
+	assert.NoError(t, lintFiles([]string{"./testdata/valid.csv"}, ""))
+}
+
+func Test_runCommandLintWithoutConfigDir(t *testing.T) {
+	// Without either of these there is no user config dir on Unix, so the history file can't be found
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	assert.NoError(t, runCommand([]string{"lint", "./testdata/valid.csv"}, commandOptions{}), "expected lint not to need the history file")
+}
@@ -2,156 +2,625 @@ package main
 
 import (
 	"bufio"
-	"bytes"
-	"encoding/csv"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
+
+	"quiz-game/quiz"
 )
 
 type quizQuestion struct {
 	question string
 	answer   string
+	// choices are rendered as a/b/c/d, and either the letter or the text of the choice can be given as the answer
+	choices []string
+	// alternatives are other answers which are also accepted as correct
+	alternatives []string
+	// matcher compares the given answer with the accepted answers, using the quiz wide -match matcher when nil
+	matcher answerMatcher
+	// limit overrides the -question-limit flag for this question when set
+	limit time.Duration
+	// difficulty chooses the questions in -adaptive mode, and weights the score so harder questions are worth more
+	difficulty difficulty
+	// tags are the topics of the question, used to filter the questions with -tags and to break down the results
+	tags []string
+	// line is where the question is defined in the quiz file, used when reporting problems
+	line int
+	// index is the question's position in the loaded quiz, which a paused quiz is saved with
+	index int
+}
+
+// outcome is the result of asking a single question.
+type outcome = quiz.Outcome
+
+const (
+	answeredCorrectly   = quiz.Correct
+	answeredIncorrectly = quiz.Incorrect
+	timedOut            = quiz.TimedOut
+)
+
+// quizSummary totals the outcomes of the questions which were asked.
+type quizSummary struct {
+	total     int
+	correct   int
+	incorrect int
+	timedOut  int
+}
+
+// add records the outcome of a single question.
+func (s *quizSummary) add(o outcome) {
+	switch o {
+	case answeredCorrectly:
+		s.correct++
+	case answeredIncorrectly:
+		s.incorrect++
+	case timedOut:
+		s.timedOut++
+	}
 }
 
 func main() {
-	var csvPath = flag.String("csv", "problems.csv", "a csv file in the format of 'question,answer' (default problems.csv)")
+	var csvPath = flag.String("csv", "problems.csv", "a quiz file in the format of 'question,answer', or a http(s) URL to fetch it from (default problems.csv)")
+	var pin = flag.String("sha256", "", "the SHA-256 hash which the quiz file must have, to check a shared quiz file hasn't been changed (default not checked)")
+	var format = flag.String("format", "", "the format of the quiz file: csv, json, yaml or markdown (default detected from the file extension)")
+	var match = flag.String("match", "exact", "how answers are compared unless a question sets its own: exact, normalize, fuzzy[:distance], numeric or regex (default exact)")
 	var limit = flag.String("limit", "30s", "the time limit for the quiz (default 30s)")
+	var questionLimit = flag.String("question-limit", "0s", "the time limit for each question, which moves on to the next question when it expires (default no limit)")
 	var random = flag.Bool("random", false, "whether to randomise the questions (default false)")
+	var reportPath = flag.String("report", "", "write a per-question report to this .json or .csv file (default no report)")
+	var player = flag.String("player", defaultPlayer(), "the name recorded against your score in the quiz history (default the logged-in user)")
+	var historyPath = flag.String("history-file", "", "the file where quiz runs are recorded (default history.jsonl in the user config dir)")
+	var addr = flag.String("addr", ":9000", "the address to listen on in serve and http mode (default :9000)")
+	var players = flag.Int("players", 2, "the number of players to wait for before starting the quiz in serve mode (default 2)")
+	var practice = flag.Bool("practice", false, "only ask the questions which are due for review, using spaced repetition (default false)")
+	var practiceSize = flag.Int("practice-size", 10, "the maximum number of questions to ask in practice mode (default 10)")
+	var practicePath = flag.String("practice-file", "", "the file where practice progress is recorded (default practice.json in the user config dir)")
+	var adaptive = flag.Bool("adaptive", false, "start with medium questions and move to harder or easier questions after each right or wrong answer (default false)")
+	var tags = flag.String("tags", "", "only ask questions tagged with at least one of these comma separated topics (default all questions)")
+	var generate = flag.String("generate", "", "generate arithmetic questions instead of using a quiz file, from a spec such as 'count=20,ops=+-*/,operands=1-12,integer' (default use the quiz file)")
+	var seed = flag.Int64("seed", 0, "the random seed used with -generate, so the same questions can be generated again (default a new seed each run)")
+	var sessionPath = flag.String("session-file", "quiz-session.json", "the file where the quiz is saved when it is paused with Ctrl-C or Ctrl-Z (default quiz-session.json)")
+	var resumePath = flag.String("resume", "", "continue a paused quiz from its session file (default start a new quiz)")
 	flag.Parse()
 
 	timeLimit, err := time.ParseDuration(*limit)
 	if err != nil {
 		log.Fatalf("Problem parsing the limit flag as a duration: %v", err)
 	}
+	perQuestionLimit, err := time.ParseDuration(*questionLimit)
+	if err != nil {
+		log.Fatalf("Problem parsing the question-limit flag as a duration: %v", err)
+	}
+
+	// Any remaining arguments are a command to run instead of the quiz e.g. 'quiz leaderboard'
+	if flag.NArg() > 0 {
+		opts := commandOptions{
+			quizPath:      *csvPath,
+			pin:           *pin,
+			format:        *format,
+			limitSet:      isFlagSet("limit"),
+			match:         *match,
+			timeLimit:     timeLimit,
+			questionLimit: perQuestionLimit,
+			random:        *random,
+			historyPath:   *historyPath,
+			addr:          *addr,
+			players:       *players,
+		}
+		if err := runCommand(flag.Args(), opts); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if *reportPath != "" {
+		if err = validateReportPath(*reportPath); err != nil {
+			log.Fatalf("Problem with the report flag: %v", err)
+		}
+	}
+
+	// A paused quiz is continued with the settings it was started with, rather than the current flags
+	var resumed *savedSession
+	if *resumePath != "" {
+		session, err := loadSession(*resumePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		resumed = &session
+
+		*csvPath, *format, *match, *player, *practice, *adaptive = session.QuizFile, session.Format, session.Match, session.Player, session.Practice, session.Adaptive
+		perQuestionLimit = time.Duration(session.QuestionLimitMs) * time.Millisecond
+		timeLimit = time.Duration(session.RemainingMs) * time.Millisecond
+		*limit = timeLimit.String()
+		*sessionPath = *resumePath
+		*generate, *seed = session.Generate, session.Seed
+		*pin, err = session.resumePin(*pin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
 
-	correctAnswers := 0
-	resultsChannel := make(chan quizQuestion)
-	completedChannel := make(chan bool)
+	run := quizRun{player: *player, reportPath: *reportPath}
+	var questions []quizQuestion
+	if *generate != "" {
+		// Generated questions are named and hashed by their spec and seed, in place of the quiz file
+		spec, err := parseGeneratorSpec(*generate)
+		if err != nil {
+			log.Fatalf("Problem with the generate flag: %v", err)
+		}
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		questions = numberQuestions(generateQuestions(spec, *seed))
+		*csvPath = generatedQuizName(*generate, *seed)
+		run.quizHash = hashGeneratedQuiz(*generate, *seed)
+		fmt.Printf("Generated %d questions, use -seed %d to generate the same questions again\n", len(questions), *seed)
+	} else {
+		// Parse the quiz file for quiz questions. A URL is fetched into the cache and loaded from there
+		quizFile, err := resolveQuizFile(*csvPath, *pin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		questions, err = loadQuestions(quizFile, *format, *match)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		run.quizHash, err = hashQuizFile(quizFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
 
-	// Parse CSV for quiz questions
-	questions, err := loadQuizFile(*csvPath)
+	run.history, err = newHistoryStore(*historyPath)
 	if err != nil {
-		log.Fatalln(err)
+		log.Printf("Quiz history will not be recorded: %v", err)
+	}
+
+	// A resumed quiz already has its questions selected and ordered
+	if resumed == nil && *tags != "" {
+		questions = filterByTags(questions, splitTags(*tags))
+		if len(questions) == 0 {
+			log.Fatalf("No questions in %s are tagged with any of: %s", *csvPath, *tags)
+		}
+	}
+
+	records := make([]answerRecord, 0, len(questions))
+	var elapsed time.Duration
+	if resumed != nil {
+		questions, records, err = resumed.restore(run.quizHash, questions)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		elapsed = time.Duration(resumed.ElapsedMs) * time.Millisecond
+		fmt.Printf("Resuming the quiz from question #%d of %d...\n", len(records)+1, len(questions))
+	}
+
+	if *practice {
+		run.practice, err = loadPracticeStore(*practicePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		run.practiceKey = practiceKey(*csvPath)
+	}
+	if *practice && resumed == nil {
+		due := run.practice.selectDue(run.practiceKey, questions, *practiceSize, time.Now())
+		if len(due) == 0 {
+			fmt.Printf("Nothing is due for review, come back after %s\n", run.practice.nextDue(run.practiceKey, questions).Format(time.RFC822))
+			return
+		}
+		fmt.Printf("Practising %d of %d questions which are due for review...\n", len(due), len(questions))
+		questions = due
 	}
 
-	if *random {
+	if *random && resumed == nil {
 		fmt.Printf("Randomising the questions...\n")
 		questions = randomiseQuestions(questions)
 	}
 
-	// Run Quiz
-	err = waitForPrompt(*limit, os.Stdin)
+	// Run Quiz. Stdin is buffered once and shared, so that answers typed ahead of the prompt aren't lost
+	stdin := bufio.NewReader(os.Stdin)
+	err = waitForPrompt(*limit, stdin)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	go askQuestions(resultsChannel, completedChannel, questions)
-	timeout := time.NewTimer(timeLimit)
+	summary := quizSummary{total: len(questions)}
+	for _, r := range records {
+		summary.add(r.outcome)
+	}
+
+	// Pause the quiz rather than exiting straight away on Ctrl-C or Ctrl-Z, so that it can be resumed later
+	pause := make(chan os.Signal, 1)
+	signal.Notify(pause, pauseSignals...)
 
-	for {
-		select {
-		// Time has run out before all the questions have been answered
-		case <-timeout.C:
-			fmt.Printf("\nTimeout!")
-			printResults(correctAnswers, len(questions))
-			os.Exit(0)
+	// startedAt includes the time spent on the quiz before it was paused, so the durations recorded are for the whole quiz
+	startedAt := time.Now().Add(-elapsed)
+	engine := &quiz.Engine[quizQuestion]{
+		In:            stdin,
+		Out:           os.Stdout,
+		Clock:         quiz.RealClock,
+		Questions:     questions,
+		Start:         len(records),
+		TimeLimit:     timeLimit,
+		QuestionLimit: perQuestionLimit,
+		Pause:         pause,
+	}
+	// In adaptive mode the questions are picked as the quiz goes, based on the answers given so far
+	if *adaptive {
+		engine.Order = newAdaptiveOrder(records)
+	}
 
-		// All the questions have been answered
-		case <-completedChannel:
-			printResults(correctAnswers, len(questions))
-			os.Exit(0)
+	result := engine.Run()
+	questions = result.Questions
+	for _, a := range result.Answers {
+		summary.add(a.Outcome)
+		records = append(records, newAnswerRecord(a))
+	}
 
-		// A single successful result has been received
-		case _, ok := <-resultsChannel:
-			// ok indicates that it received an event rather than a zero value caused by the channel closing
-			if ok {
-				correctAnswers++
-			}
+	switch result.Status {
+	// Time has run out before all the questions have been answered
+	case quiz.OutOfTime:
+		fmt.Printf("\nTimeout!")
+
+	// The quiz has been paused. The question being asked is discarded, and will be asked again when resuming
+	case quiz.Paused:
+		err = saveSession(*sessionPath, savedSession{
+			QuizFile:        *csvPath,
+			QuizHash:        run.quizHash,
+			Format:          *format,
+			Match:           *match,
+			Player:          *player,
+			Practice:        *practice,
+			Adaptive:        *adaptive,
+			Generate:        *generate,
+			Seed:            *seed,
+			Pin:             *pin,
+			QuestionLimitMs: perQuestionLimit.Milliseconds(),
+			RemainingMs:     (timeLimit - result.Elapsed).Milliseconds(),
+			ElapsedMs:       (elapsed + result.Elapsed).Milliseconds(),
+			PausedAt:        time.Now(),
+			Order:           questionIndexes(questions),
+			Questions:       questionOrder(questions),
+			Answers:         savedAnswers(records),
+		})
+		if err != nil {
+			log.Fatalln(err)
 		}
+		fmt.Printf("\nQuiz paused, continue with -resume %s\n", *sessionPath)
+		return
 	}
+
+	finishQuiz(run, buildReport(*csvPath, startedAt, questions, records), summary)
+	removeSession(*resumePath)
 }
 
-// loadQuizFile loads a CSV file from csvPath and returns them as a slice a quizQuestion.
-func loadQuizFile(csvPath string) ([]quizQuestion, error) {
-	questions := make([]quizQuestion, 0)
+// loadQuizFile loads the quiz file at path and returns its questions as a slice of quizQuestion.
+// The loader is chosen by format, or by the file extension when format is empty. Rows which could not be loaded
+// are returned alongside the questions so that they can be reported to the user.
+func loadQuizFile(path, format string) ([]quizQuestion, []skippedRow, error) {
+	source, err := sourceFor(path, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem reading the quiz file at '%s': %v", path, err)
+	}
+	defer f.Close()
 
-	b, err := os.ReadFile(csvPath)
+	questions, skipped, err := source.Load(f)
 	if err != nil {
-		return questions, fmt.Errorf("problem reading the quiz file at '%s': %v", csvPath, err)
+		return nil, nil, fmt.Errorf("problem loading the quiz file at '%s': %v", path, err)
 	}
 
-	reader := csv.NewReader(bytes.NewReader(b))
-	// Could have used reader.ReadAll instead to avoid iterating, which creates a slice (for each row) of slices (for each field)
-	for {
-		csvRecord, err := reader.Read()
-		if err == io.EOF {
-			break
+	return questions, skipped, nil
+}
+
+// quizRun holds the details of the current run which are needed once the quiz has finished.
+type quizRun struct {
+	player     string
+	quizHash   string
+	reportPath string
+	history    historyStore
+	// practice and practiceKey are only set in practice mode
+	practice    *practiceStore
+	practiceKey string
+}
+
+// commandOptions are the flags which are used by the commands.
+type commandOptions struct {
+	quizPath string
+	pin      string
+	format   string
+	match    string
+	// limitSet is whether -limit was given, as the quiz wide limit isn't used by every command
+	limitSet      bool
+	timeLimit     time.Duration
+	questionLimit time.Duration
+	random        bool
+	historyPath   string
+	addr          string
+	players       int
+}
+
+// isFlagSet reports whether the flag called name was given on the command line, rather than left as its default.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
-		// CSV packages expects all the records to have the same number of fields. Skip any that are irregular
+	})
+
+	return set
+}
+
+// loadHistory returns every run in the history file at path, or in the user config dir when path is empty. Only the
+// commands which show the history use it, so the others work without a config dir.
+func loadHistory(path string) ([]historyEntry, error) {
+	history, err := newHistoryStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return history.Load()
+}
+
+// runCommand runs one of the commands which can be used instead of taking the quiz.
+func runCommand(args []string, opts commandOptions) error {
+	switch args[0] {
+	case "history":
+		entries, err := loadHistory(opts.historyPath)
 		if err != nil {
-			continue
+			return err
 		}
-		// Expect exactly one question and answer in the CSV record
-		if len(csvRecord) != 2 {
-			continue
+		// An optional player name limits the history to their runs
+		player := ""
+		if len(args) > 1 {
+			player = args[1]
 		}
+		printHistory(entries, player)
+
+	case "leaderboard":
+		entries, err := loadHistory(opts.historyPath)
+		if err != nil {
+			return err
+		}
+		printLeaderboard(entries)
+
+	case "lint":
+		return lintFiles(args[1:], opts.format)
 
-		q := quizQuestion{question: csvRecord[0], answer: csvRecord[1]}
-		questions = append(questions, q)
+	case "serve":
+		// Each round has its own limit instead, so a quiz wide limit would be silently ignored
+		if opts.limitSet {
+			return fmt.Errorf("-limit can't be used with serve, use -question-limit to limit each round")
+		}
+
+		questions, err := resolveAndLoadQuestions(opts)
+		if err != nil {
+			return err
+		}
+
+		listener, err := net.Listen("tcp", opts.addr)
+		if err != nil {
+			return fmt.Errorf("listening on %s: %v", opts.addr, err)
+		}
+		defer listener.Close()
+
+		log.Printf("Hosting %s on %s, waiting for %d players", opts.quizPath, listener.Addr(), opts.players)
+		server := newQuizServer(listener, questions, opts.players, opts.questionLimit)
+		_, err = server.run()
+		return err
+
+	case "http":
+		questions, err := resolveAndLoadQuestions(opts)
+		if err != nil {
+			return err
+		}
+
+		server, err := newWebServer(opts.quizPath, questions, opts.timeLimit, opts.random)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Serving %s over HTTP on %s", opts.quizPath, opts.addr)
+		if err = http.ListenAndServe(opts.addr, server.routes()); err != nil {
+			return fmt.Errorf("running web server: %v", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command '%s', expected one of: history, leaderboard, lint, serve, http", args[0])
 	}
-	return questions, nil
-}
 
-// printResults prints out the number of correctly answered questions vs total quiz questions.
-func printResults(correctAnswers, totalNumber int) {
-	fmt.Printf("\nYou answered %d out of %d correct!", correctAnswers, totalNumber)
+	return nil
 }
 
-// askQuestions iterates through the questions and calls checkAnswer for each one.
-// Correctly answered questions are sent to the rc channel (to be totalled by the main go routine).
-// When all questions have been processed an event is sent to the cc channel to indicate completion.
-func askQuestions(rc chan quizQuestion, cc chan bool, questions []quizQuestion) {
-	for i, question := range questions {
-		result := checkAnswer(question, i, os.Stdin)
-		// Send correct answers via the channel to be totalled in the main go routine
-		if result {
-			rc <- question
+// finishQuiz prints the per-question report and the overall results, records the run in the quiz history (or the
+// practice progress in practice mode) and then exports the report if requested. Practice runs are left out of the
+// history, as they only ask the questions which are due so their scores can't be compared with full runs.
+func finishQuiz(run quizRun, report quizReport, summary quizSummary) {
+	printReport(report)
+	printResults(summary)
+
+	if run.history.path != "" && run.practice == nil {
+		entry := historyEntry{
+			Player:     run.player,
+			QuizFile:   report.QuizFile,
+			QuizHash:   run.quizHash,
+			Correct:    summary.correct,
+			Total:      summary.total,
+			DurationMs: time.Since(report.StartedAt).Milliseconds(),
+			FinishedAt: time.Now(),
 		}
+		if report.rated() {
+			entry.Score, entry.MaxScore = report.Score, report.MaxScore
+		}
+		if err := run.history.Append(entry); err != nil {
+			log.Printf("Problem recording the quiz history: %v", err)
+		}
+	}
+
+	if run.practice != nil {
+		run.practice.record(run.practiceKey, report, time.Now())
+		if err := run.practice.save(); err != nil {
+			log.Printf("Problem recording the practice progress: %v", err)
+		}
+	}
+
+	if run.reportPath == "" {
+		return
+	}
+	if err := writeReport(run.reportPath, report); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("Report written to %s\n", run.reportPath)
+}
+
+// resolveAndLoadQuestions loads the questions for a command, fetching the quiz file first if it is a URL.
+func resolveAndLoadQuestions(opts commandOptions) ([]quizQuestion, error) {
+	quizFile, err := resolveQuizFile(opts.quizPath, opts.pin)
+	if err != nil {
+		return nil, err
 	}
-	close(rc)
 
-	// Indicate we have processed all the quiz questions
-	cc <- true
-	close(cc)
+	return loadQuestions(quizFile, opts.format, opts.match)
 }
 
-// checkAnswer asks the user a question on the terminal and inspects the response via stdin.
-// All whitespace and case or ignored when comparing answers.
-func checkAnswer(question quizQuestion, number int, reader io.Reader) bool {
-	fmt.Printf("Question #%d: %s = ", number+1, question.question)
+// loadQuestions calls loadQuizFile and logs any rows which were skipped. match is the matcher spec used for
+// questions which don't set their own.
+func loadQuestions(path, format, match string) ([]quizQuestion, error) {
+	matcher, err := parseMatcher(match)
+	if err != nil {
+		return nil, err
+	}
 
-	// could have used fmt.Scanf instead as only using single words
-	readStdin := bufio.NewReader(reader)
-	// Using ReadLine does not include the trailing \n like with ReadString
-	answer, _, err := readStdin.ReadLine()
+	questions, skipped, err := loadQuizFile(path, format)
 	if err != nil {
-		log.Printf("problem reading input from stdin: %v", err)
-		return false
+		return nil, err
+	}
+	for _, s := range skipped {
+		log.Printf("Skipping %s %s", path, s)
 	}
-	// Trim all whitespace and ignore case
-	if strings.TrimSpace(strings.ToLower(string(answer))) == strings.TrimSpace(strings.ToLower(question.answer)) {
-		return true
+
+	if matcher == nil {
+		return numberQuestions(questions), nil
+	}
+
+	matched := make([]quizQuestion, 0, len(questions))
+	for _, q := range questions {
+		if q.matcher == nil {
+			// Skip any questions whose answers can't be used with the quiz wide matcher e.g. text with -match numeric
+			if err := validateAnswers(q, matcher); err != nil {
+				log.Printf("Skipping %s %s", path, skippedRow{line: q.line, reason: err.Error()})
+				continue
+			}
+			q.matcher = matcher
+		}
+		matched = append(matched, q)
+	}
+
+	return numberQuestions(matched), nil
+}
+
+// numberQuestions sets the index of each question to its position in questions.
+func numberQuestions(questions []quizQuestion) []quizQuestion {
+	for i := range questions {
+		questions[i].index = i
+	}
+
+	return questions
+}
+
+// printResults prints out the number of correctly answered questions vs total quiz questions,
+// along with a breakdown of the questions which were not answered correctly.
+func printResults(s quizSummary) {
+	fmt.Printf("\nYou answered %d out of %d correct!", s.correct, s.total)
+
+	unanswered := s.total - s.correct - s.incorrect - s.timedOut
+	fmt.Printf("\nWrong: %d, Timed out: %d, Unanswered: %d\n", s.incorrect, s.timedOut, unanswered)
+}
+
+// Prompt returns the text used to ask a question, including any multiple choice options.
+func (q quizQuestion) Prompt(number int) string {
+	if len(q.choices) == 0 {
+		return fmt.Sprintf("Question #%d: %s = ", number+1, q.question)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Question #%d: %s\n", number+1, q.question)
+	for i, c := range q.choices {
+		fmt.Fprintf(&b, "  %c) %s\n", choiceLetter(i), c)
+	}
+	fmt.Fprintf(&b, "Choose a-%c: ", choiceLetter(len(q.choices)-1))
+
+	return b.String()
+}
+
+// choiceLetter returns the letter used to label the choice at index i.
+func choiceLetter(i int) rune {
+	return rune('a' + i)
+}
+
+// IsCorrect reports whether answer matches the expected answer or any of the alternatives, using the question's
+// matcher. By default all whitespace and case are ignored when comparing answers.
+func (q quizQuestion) IsCorrect(answer string) bool {
+	given := answer
+	if choice, ok := q.choiceFor(answer); ok {
+		given = choice
+	}
+
+	matcher := q.matcher
+	if matcher == nil {
+		matcher = exactMatcher{}
+	}
+
+	for _, accepted := range q.acceptedAnswers() {
+		if matcher.matches(given, accepted) {
+			return true
+		}
 	}
 
 	return false
 }
 
+// Limit returns the time allowed for this question, or zero when the -question-limit flag applies.
+func (q quizQuestion) Limit() time.Duration {
+	return q.limit
+}
+
+// acceptedAnswers returns the expected answer followed by any alternatives.
+func (q quizQuestion) acceptedAnswers() []string {
+	return append([]string{q.answer}, q.alternatives...)
+}
+
+// choiceFor returns the text of the choice selected by answer, which can be either the choice's letter or its text.
+func (q quizQuestion) choiceFor(answer string) (string, bool) {
+	a := normaliseAnswer(answer)
+
+	for i, c := range q.choices {
+		if a == string(choiceLetter(i)) || a == normaliseAnswer(c) {
+			return c, true
+		}
+	}
+
+	return "", false
+}
+
+// normaliseAnswer trims all surrounding whitespace and ignores case.
+func normaliseAnswer(answer string) string {
+	return strings.TrimSpace(strings.ToLower(answer))
+}
+
 // waitForPrompt prompts the user to press any key before the quiz (and timer) starts.
 func waitForPrompt(duration string, reader io.Reader) error {
 	fmt.Printf("Enter any key to start timer (%s): ", duration)
@@ -5,27 +5,38 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"quiz-game/quiz"
 )
 
 func Test_loadQuizFile(t *testing.T) {
 	var tests = []struct {
 		testName                  string
 		csvPath                   string
+		format                    string
 		errorExpected             bool
 		expectedNumberOfQuestions int
+		expectedSkippedLines      []int
 	}{
 		{testName: "invalid-csv-path", csvPath: "invalid-path.csv", errorExpected: true, expectedNumberOfQuestions: 0},
 		{testName: "valid-csv", csvPath: "./testdata/valid.csv", errorExpected: false, expectedNumberOfQuestions: 3},
-		{testName: "invalid-single-record-should-be-excluded", csvPath: "./testdata/bad-record.csv", errorExpected: false, expectedNumberOfQuestions: 2},
+		{testName: "invalid-single-record-should-be-excluded", csvPath: "./testdata/bad-record.csv", errorExpected: false, expectedNumberOfQuestions: 2, expectedSkippedLines: []int{3}},
 		{testName: "empty-csv", csvPath: "./testdata/empty.csv", errorExpected: false, expectedNumberOfQuestions: 0},
-		{testName: "too-many-fields", csvPath: "./testdata/too-many-fields.csv", errorExpected: false, expectedNumberOfQuestions: 0},
+		{testName: "too-many-fields", csvPath: "./testdata/too-many-fields.csv", errorExpected: false, expectedNumberOfQuestions: 0, expectedSkippedLines: []int{1, 2}},
+		{testName: "csv-with-header", csvPath: "./testdata/extended.csv", errorExpected: false, expectedNumberOfQuestions: 3, expectedSkippedLines: []int{5, 6}},
+		{testName: "valid-json", csvPath: "./testdata/valid.json", errorExpected: false, expectedNumberOfQuestions: 3},
+		{testName: "valid-yaml", csvPath: "./testdata/valid.yaml", errorExpected: false, expectedNumberOfQuestions: 3},
+		{testName: "valid-markdown", csvPath: "./testdata/valid.md", errorExpected: false, expectedNumberOfQuestions: 3},
+		{testName: "format-flag-overrides-extension", csvPath: "./testdata/valid.csv", format: "json", errorExpected: true, expectedNumberOfQuestions: 0},
+		{testName: "unknown-extension", csvPath: "./testdata/unknown.txt", errorExpected: true, expectedNumberOfQuestions: 0},
 	}
 
 	for _, e := range tests {
-		questions, err := loadQuizFile(e.csvPath)
+		questions, skipped, err := loadQuizFile(e.csvPath, e.format)
 
 		if e.errorExpected {
 			assert.Error(t, err, fmt.Sprintf("%s: expected an error when trying to open CSV", e.testName))
@@ -36,6 +47,15 @@ func Test_loadQuizFile(t *testing.T) {
 		}
 
 		assert.Equal(t, e.expectedNumberOfQuestions, len(questions), fmt.Sprintf("%s: unexpected number of records returned", e.testName))
+
+		skippedLines := make([]int, 0)
+		for _, s := range skipped {
+			skippedLines = append(skippedLines, s.line)
+		}
+		if e.expectedSkippedLines == nil {
+			e.expectedSkippedLines = []int{}
+		}
+		assert.Equal(t, e.expectedSkippedLines, skippedLines, fmt.Sprintf("%s: unexpected skipped lines", e.testName))
 	}
 }
 
@@ -52,18 +72,34 @@ func Test_checkAnswer(t *testing.T) {
 		{testName: "ignores-case", question: quizQuestion{question: "are you male?", answer: "yes"}, userInput: "YES\n", questionNumber: 2, expectedResponse: true},
 		{testName: "ignores-case-and-whitespace", question: quizQuestion{question: "are you male?", answer: " yes "}, userInput: " YES \n", questionNumber: 3, expectedResponse: true},
 		{testName: "incorrect-answer", question: quizQuestion{question: "1+1", answer: "2"}, userInput: "abc\n", questionNumber: 4, expectedResponse: false},
+		{testName: "choice-letter", question: quizQuestion{question: "capital of France?", answer: "Paris", choices: []string{"London", "Paris"}}, userInput: "B\n", questionNumber: 5, expectedResponse: true},
+		{testName: "choice-text", question: quizQuestion{question: "capital of France?", answer: "Paris", choices: []string{"London", "Paris"}}, userInput: "paris\n", questionNumber: 6, expectedResponse: true},
+		{testName: "wrong-choice-letter", question: quizQuestion{question: "capital of France?", answer: "Paris", choices: []string{"London", "Paris"}}, userInput: "a\n", questionNumber: 7, expectedResponse: false},
+		{testName: "alternative-answer", question: quizQuestion{question: "largest ocean?", answer: "Pacific", alternatives: []string{"Pacific Ocean"}}, userInput: "pacific ocean\n", questionNumber: 8, expectedResponse: true},
+		{testName: "numeric-within-tolerance", question: quizQuestion{question: "pi to 2dp?", answer: "3.14", matcher: numericMatcher{tolerance: 0.01}}, userInput: "3.15\n", questionNumber: 9, expectedResponse: true},
+		{testName: "numeric-outside-tolerance", question: quizQuestion{question: "pi to 2dp?", answer: "3.14", matcher: numericMatcher{tolerance: 0.01}}, userInput: "3.2\n", questionNumber: 10, expectedResponse: false},
+		{testName: "numeric-equivalent-format", question: quizQuestion{question: "5+5", answer: "10", matcher: numericMatcher{}}, userInput: "10.0\n", questionNumber: 11, expectedResponse: true},
 	}
 
 	for _, e := range tests {
 		var buf bytes.Buffer
 		buf.WriteString(e.userInput)
 
-		result := checkAnswer(e.question, e.questionNumber, &buf)
+		result := quiz.Ask(io.Discard, quiz.RealClock, e.question, e.questionNumber, quiz.ReadLines(&buf), 0)
 
-		assert.Equal(t, e.expectedResponse, result, fmt.Sprintf("%s: unexpected response from checkAnswer", e.testName))
+		assert.Equal(t, e.expectedResponse, result.Outcome == answeredCorrectly, fmt.Sprintf("%s: unexpected response from checkAnswer", e.testName))
 	}
 }
 
+func Test_quizSummary(t *testing.T) {
+	s := quizSummary{total: 4}
+	for _, o := range []outcome{answeredCorrectly, answeredIncorrectly, timedOut} {
+		s.add(o)
+	}
+
+	assert.Equal(t, quizSummary{total: 4, correct: 1, incorrect: 1, timedOut: 1}, s)
+}
+
 func Test_waitForPrompt(t *testing.T) {
 	// Test user input and check what is being written by the app to stdout
 	oldOut := os.Stdout
@@ -103,7 +139,7 @@ func compareSlices(original, target []quizQuestion) bool {
 	for _, q := range original {
 		found := false
 		for i2, t := range target {
-			if q == t {
+			if reflect.DeepEqual(q, t) {
 				// found element. pop and break
 				copy(target[i2:], target[i2+1:])       // Shift left one index.
 				target[len(target)-1] = quizQuestion{} // Erase last element (write zero value).
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// answerMatcher decides whether an answer given by the user matches one of the accepted answers for a question.
+// A matcher can be chosen for the whole quiz with -match, or for a single question in the quiz file.
+type answerMatcher interface {
+	// matches reports whether the given answer matches an accepted answer
+	matches(given, accepted string) bool
+	// validate checks that an accepted answer can be used with the matcher, so problems are found when loading
+	validate(accepted string) error
+}
+
+// parseMatcher returns the matcher for a spec such as 'exact', 'normalize', 'fuzzy:2', 'numeric' or 'regex'.
+// An empty spec returns nil so that the quiz wide matcher is used instead.
+func parseMatcher(spec string) (answerMatcher, error) {
+	name, arg, hasArg := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), ":")
+
+	switch name {
+	case "":
+		return nil, nil
+	case "exact":
+		return exactMatcher{}, nil
+	case "normalize", "normalise":
+		return normalizedMatcher{}, nil
+	case "numeric":
+		return numericMatcher{}, nil
+	case "regex":
+		return regexMatcher{}, nil
+	case "fuzzy":
+		if !hasArg {
+			return fuzzyMatcher{distance: -1}, nil
+		}
+		distance, err := strconv.Atoi(arg)
+		if err != nil || distance < 0 {
+			return nil, fmt.Errorf("invalid fuzzy distance '%s', expected a whole number such as fuzzy:2", arg)
+		}
+		return fuzzyMatcher{distance: distance}, nil
+	default:
+		return nil, fmt.Errorf("unknown matcher '%s', expected one of: exact, normalize, fuzzy[:distance], numeric, regex", spec)
+	}
+}
+
+// exactMatcher is the default, and ignores case and any surrounding whitespace.
+type exactMatcher struct{}
+
+func (exactMatcher) matches(given, accepted string) bool {
+	return normaliseAnswer(given) == normaliseAnswer(accepted)
+}
+
+func (exactMatcher) validate(string) error {
+	return nil
+}
+
+// normalizedMatcher also ignores accents, differences in Unicode normalisation and repeated whitespace,
+// so 'Café  au lait' matches 'cafe au lait'.
+type normalizedMatcher struct{}
+
+func (normalizedMatcher) matches(given, accepted string) bool {
+	return foldAnswer(given) == foldAnswer(accepted)
+}
+
+func (normalizedMatcher) validate(string) error {
+	return nil
+}
+
+// fuzzyMatcher accepts answers within a Levenshtein distance of the accepted answer, after normalising both.
+// A negative distance allows one typo for every five characters of the accepted answer, so short answers
+// (where a single typo can give a different but valid answer) must still be exact.
+type fuzzyMatcher struct {
+	distance int
+}
+
+func (m fuzzyMatcher) matches(given, accepted string) bool {
+	g, a := foldAnswer(given), foldAnswer(accepted)
+
+	allowed := m.distance
+	if allowed < 0 {
+		allowed = len([]rune(a)) / 5
+	}
+
+	return levenshtein(g, a) <= allowed
+}
+
+func (fuzzyMatcher) validate(string) error {
+	return nil
+}
+
+// numericMatcher compares answers as numbers, accepting any number within tolerance of the accepted answer.
+// Numbers can be given in digits or words, so '10', '10.0' and 'ten' are all equivalent.
+type numericMatcher struct {
+	tolerance float64
+}
+
+func (m numericMatcher) matches(given, accepted string) bool {
+	g, err := parseNumber(given)
+	if err != nil {
+		return false
+	}
+	a, err := parseNumber(accepted)
+	if err != nil {
+		return false
+	}
+
+	return math.Abs(g-a) <= m.tolerance
+}
+
+func (numericMatcher) validate(accepted string) error {
+	if _, err := parseNumber(accepted); err != nil {
+		return fmt.Errorf("answer '%s' must be a number for the numeric matcher", accepted)
+	}
+
+	return nil
+}
+
+// regexMatcher treats each accepted answer as a case-insensitive regular expression which must match the whole
+// of the given answer.
+type regexMatcher struct{}
+
+func (regexMatcher) matches(given, accepted string) bool {
+	re, err := compileAnswerRegex(accepted)
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(strings.TrimSpace(given))
+}
+
+func (regexMatcher) validate(accepted string) error {
+	if _, err := compileAnswerRegex(accepted); err != nil {
+		return fmt.Errorf("answer '%s' is not a valid regular expression: %v", accepted, err)
+	}
+
+	return nil
+}
+
+// compileAnswerRegex anchors the pattern so that it has to match the whole answer.
+func compileAnswerRegex(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`(?i)^(?:` + strings.TrimSpace(pattern) + `)$`)
+}
+
+// foldAnswer lower cases an answer, removes accents and collapses all whitespace to single spaces.
+func foldAnswer(answer string) string {
+	// Decompose characters such as 'é' into 'e' plus a combining accent, and then drop the accents
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, answer)
+	if err != nil {
+		folded = answer
+	}
+
+	return strings.Join(strings.Fields(strings.ToLower(folded)), " ")
+}
+
+// levenshtein returns the number of single character edits needed to turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Only the previous row of the edit distance table is needed to calculate the next one
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(rb)]
+}
+
+var numberWords = map[string]float64{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+	"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+	"seventeen": 17, "eighteen": 18, "nineteen": 19, "twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+var numberScales = map[string]float64{
+	"hundred": 100, "thousand": 1_000, "million": 1_000_000, "billion": 1_000_000_000,
+}
+
+// parseNumber parses a number written either in digits (e.g. '10', '10.0', '1,000', '1,5') or in English words
+// (e.g. 'ten', 'twenty-one', 'one hundred and five').
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+
+	if digits, ok := withoutCommas(s); ok {
+		// ParseFloat also reads 'nan' and 'inf', which can't be compared as answers
+		if n, err := strconv.ParseFloat(digits, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			return n, nil
+		}
+	}
+
+	words := strings.Fields(strings.ReplaceAll(s, "-", " "))
+	if len(words) == 0 {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	}
+
+	sign := 1.0
+	if words[0] == "minus" || words[0] == "negative" {
+		sign = -1
+		words = words[1:]
+	}
+
+	var total, current float64
+	seenNumber := false
+	for _, w := range words {
+		if w == "and" {
+			continue
+		}
+		if n, ok := numberWords[w]; ok {
+			current += n
+			seenNumber = true
+			continue
+		}
+		scale, ok := numberScales[w]
+		if !ok || !seenNumber {
+			return 0, fmt.Errorf("'%s' is not a number", s)
+		}
+		if scale == 100 {
+			current *= scale
+			continue
+		}
+		total += current * scale
+		current = 0
+	}
+	if !seenNumber {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	}
+
+	return sign * (total + current), nil
+}
+
+// withoutCommas rewrites the commas in a number written in digits into the form ParseFloat expects. A single comma
+// followed by one or two digits is a decimal point (e.g. '1,5'), while commas between groups of three digits are
+// thousands separators (e.g. '1,000,000'). Any other use of commas is ambiguous, so ok is false.
+func withoutCommas(s string) (digits string, ok bool) {
+	if !strings.Contains(s, ",") {
+		return s, true
+	}
+
+	groups := strings.Split(s, ",")
+	if len(groups) == 2 && !strings.Contains(s, ".") && len(groups[1]) >= 1 && len(groups[1]) <= 2 {
+		return groups[0] + "." + groups[1], true
+	}
+
+	for i, g := range groups[1:] {
+		// Only the last group can have a decimal part after its three digits
+		if i == len(groups)-2 {
+			g, _, _ = strings.Cut(g, ".")
+		}
+		if len(g) != 3 {
+			return "", false
+		}
+	}
+
+	return strings.Join(groups, ""), true
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_matchers(t *testing.T) {
+	tt := []struct {
+		name     string
+		spec     string
+		given    string
+		accepted string
+		expected bool
+	}{
+		{name: "exact ignores case", spec: "exact", given: " PARIS ", accepted: "paris", expected: true},
+		{name: "exact keeps accents", spec: "exact", given: "cafe", accepted: "café", expected: false},
+		{name: "normalize folds accents", spec: "normalize", given: "Cafe  au   lait", accepted: "café au lait", expected: true},
+		{name: "normalize decomposed input", spec: "normalize", given: "José", accepted: "josé", expected: true},
+		{name: "fuzzy allows a typo", spec: "fuzzy:1", given: "Pacfic", accepted: "pacific", expected: true},
+		{name: "fuzzy limits typos", spec: "fuzzy:1", given: "Pcfic", accepted: "pacific", expected: false},
+		{name: "fuzzy default scales with length", spec: "fuzzy", given: "Mediteranean", accepted: "Mediterranean", expected: true},
+		{name: "fuzzy default exact for short answers", spec: "fuzzy", given: "5", accepted: "4", expected: false},
+		{name: "fuzzy folds accents", spec: "fuzzy:0", given: "Zurich", accepted: "Zürich", expected: true},
+		{name: "numeric decimal", spec: "numeric", given: "10.0", accepted: "10", expected: true},
+		{name: "numeric words", spec: "numeric", given: "ten", accepted: "10", expected: true},
+		{name: "numeric compound words", spec: "numeric", given: "one hundred and twenty-one", accepted: "121", expected: true},
+		{name: "numeric thousands separator", spec: "numeric", given: "1,000", accepted: "one thousand", expected: true},
+		{name: "numeric thousands with decimals", spec: "numeric", given: "1,000.5", accepted: "1000.5", expected: true},
+		{name: "numeric decimal comma", spec: "numeric", given: "1,5", accepted: "1.5", expected: true},
+		{name: "numeric decimal comma two digits", spec: "numeric", given: "3,14", accepted: "3.14", expected: true},
+		{name: "numeric decimal comma isn't thousands", spec: "numeric", given: "1,5", accepted: "15", expected: false},
+		{name: "numeric ambiguous commas", spec: "numeric", given: "1,50,0", accepted: "1500", expected: false},
+		{name: "numeric wrong", spec: "numeric", given: "eleven", accepted: "10", expected: false},
+		{name: "numeric not a number", spec: "numeric", given: "lots", accepted: "10", expected: false},
+		{name: "numeric nan", spec: "numeric", given: "nan", accepted: "NaN", expected: false},
+		{name: "numeric infinity", spec: "numeric", given: "inf", accepted: "+Inf", expected: false},
+		{name: "regex", spec: "regex", given: "The Beatles", accepted: "(the )?beatles", expected: true},
+		{name: "regex anchored", spec: "regex", given: "not the beatles", accepted: "(the )?beatles", expected: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := parseMatcher(tc.spec)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, m.matches(tc.given, tc.accepted))
+		})
+	}
+}
+
+func Test_parseMatcher(t *testing.T) {
+	m, err := parseMatcher("")
+	assert.NoError(t, err)
+	assert.Nil(t, m, "expected no matcher so that the quiz wide matcher is used")
+
+	for _, spec := range []string{"fuzzy:x", "fuzzy:-1", "soundex"} {
+		_, err = parseMatcher(spec)
+		assert.Error(t, err, "expected an error for %s", spec)
+	}
+
+	assert.Error(t, numericMatcher{}.validate("ten apples"))
+	for _, accepted := range []string{"nan", "inf", "+Inf", "-infinity"} {
+		assert.Error(t, numericMatcher{}.validate(accepted), "expected %s not to be accepted as a number", accepted)
+	}
+	assert.Error(t, regexMatcher{}.validate("(unclosed"))
+}
+
+func Test_levenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("", ""))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+	assert.Equal(t, 1, levenshtein("über", "uber"), "expected distances to be counted in runes rather than bytes")
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const practiceFileName = "practice.json"
+
+// leitnerIntervals is how long to wait before asking a question again, indexed by its Leitner box. Questions start
+// in box 1, move up a box each time they are answered correctly and go back to box 1 whenever they are missed,
+// so the questions people keep missing come back more often.
+var leitnerIntervals = []time.Duration{
+	1: 0,
+	2: 24 * time.Hour,
+	3: 3 * 24 * time.Hour,
+	4: 7 * 24 * time.Hour,
+	5: 14 * 24 * time.Hour,
+}
+
+// practiceCard is the spaced repetition state of a single question.
+type practiceCard struct {
+	Box       int       `json:"box"`
+	Due       time.Time `json:"due"`
+	Correct   int       `json:"correct"`
+	Incorrect int       `json:"incorrect"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// review moves the card between boxes based on whether it was answered correctly, and schedules the next review.
+func (c practiceCard) review(correct bool, now time.Time) practiceCard {
+	if c.Box < 1 {
+		c.Box = 1
+	}
+
+	if correct {
+		c.Correct++
+		if c.Box < len(leitnerIntervals)-1 {
+			c.Box++
+		}
+	} else {
+		c.Incorrect++
+		c.Box = 1
+	}
+
+	c.LastSeen = now
+	c.Due = now.Add(leitnerIntervals[c.Box])
+
+	return c
+}
+
+// practiceKey returns the key which a quiz's cards are kept under. A quiz file is keyed by its absolute path rather
+// than by its content, so that fixing a typo or adding a question doesn't start every card over, and anything else
+// (such as a URL) is keyed by its name. The cards within a quiz are keyed by the question text.
+func practiceKey(quizPath string) string {
+	if _, err := os.Stat(quizPath); err != nil {
+		return quizPath
+	}
+	abs, err := filepath.Abs(quizPath)
+	if err != nil {
+		return quizPath
+	}
+
+	return abs
+}
+
+// practiceStore persists the cards for every quiz, keyed by practiceKey and then by the question text.
+type practiceStore struct {
+	path  string
+	cards map[string]map[string]practiceCard
+}
+
+// loadPracticeStore reads the practice file at path, or from the user's config dir when path is empty.
+// A missing file is treated as no practice so far.
+func loadPracticeStore(path string) (*practiceStore, error) {
+	if path == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("finding the user config dir: %v", err)
+		}
+		path = filepath.Join(configDir, configDirName, practiceFileName)
+	}
+
+	store := &practiceStore{path: path, cards: make(map[string]map[string]practiceCard)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading practice file '%s': %v", path, err)
+	}
+
+	if err = json.Unmarshal(b, &store.cards); err != nil {
+		return nil, fmt.Errorf("decoding practice file '%s': %v", path, err)
+	}
+
+	return store, nil
+}
+
+// save writes every card back to the practice file.
+func (s *practiceStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating practice dir: %v", err)
+	}
+
+	b, err := json.MarshalIndent(s.cards, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding practice file: %v", err)
+	}
+
+	// Write to a temporary file first so that an interrupted save can't lose the existing progress
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("writing practice file '%s': %v", tmp, err)
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replacing practice file '%s': %v", s.path, err)
+	}
+
+	return nil
+}
+
+// card returns the card for a question, which starts in box 1 and is due straight away if it has never been seen.
+func (s *practiceStore) card(quizKey, question string) practiceCard {
+	if c, ok := s.cards[quizKey][question]; ok {
+		return c
+	}
+
+	return practiceCard{Box: 1}
+}
+
+// selectDue returns up to size questions which are due for review. Questions in the lowest boxes (the ones which
+// have been missed most recently) come first, followed by the ones which have been due the longest.
+func (s *practiceStore) selectDue(quizKey string, questions []quizQuestion, size int, now time.Time) []quizQuestion {
+	due := make([]quizQuestion, 0)
+	for _, q := range questions {
+		if !s.card(quizKey, q.question).Due.After(now) {
+			due = append(due, q)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		a, b := s.card(quizKey, due[i].question), s.card(quizKey, due[j].question)
+		if a.Box != b.Box {
+			return a.Box < b.Box
+		}
+		return a.Due.Before(b.Due)
+	})
+
+	if size > 0 && len(due) > size {
+		due = due[:size]
+	}
+
+	return due
+}
+
+// nextDue returns when the next question will be due for review, or the zero time if there are no questions.
+func (s *practiceStore) nextDue(quizKey string, questions []quizQuestion) time.Time {
+	var next time.Time
+	for _, q := range questions {
+		due := s.card(quizKey, q.question).Due
+		if next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+
+	return next
+}
+
+// record updates the cards using the results of a quiz run. Questions which were never answered are left unchanged.
+func (s *practiceStore) record(quizKey string, report quizReport, now time.Time) {
+	if s.cards[quizKey] == nil {
+		s.cards[quizKey] = make(map[string]practiceCard)
+	}
+
+	for _, row := range report.Questions {
+		if row.Result == "unanswered" {
+			continue
+		}
+		s.cards[quizKey][row.Question] = s.card(quizKey, row.Question).review(row.Result == answeredCorrectly.String(), now)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_practiceCardReview(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := practiceCard{}.review(true, now)
+	assert.Equal(t, 2, c.Box, "expected a correct answer to move the card up a box")
+	assert.Equal(t, now.Add(24*time.Hour), c.Due)
+
+	for i := 0; i < 10; i++ {
+		c = c.review(true, now)
+	}
+	assert.Equal(t, 5, c.Box, "expected the card to stop at the last box")
+	assert.Equal(t, 11, c.Correct)
+
+	c = c.review(false, now)
+	assert.Equal(t, 1, c.Box, "expected a wrong answer to move the card back to the first box")
+	assert.Equal(t, now, c.Due, "expected a missed question to be due straight away")
+	assert.Equal(t, 1, c.Incorrect)
+}
+
+func Test_practiceStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "practice.json")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	questions := []quizQuestion{{question: "1+1", answer: "2"}, {question: "2+2", answer: "4"}, {question: "3+3", answer: "6"}}
+
+	store, err := loadPracticeStore(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(store.selectDue("quiz", questions, 10, now)), "expected every question to be due before any practice")
+
+	report := quizReport{Questions: []reportRow{
+		{Question: "1+1", Result: "correct"},
+		{Question: "2+2", Result: "incorrect"},
+		{Question: "3+3", Result: "unanswered"},
+	}}
+	store.record("quiz", report, now)
+	assert.NoError(t, store.save())
+
+	reloaded, err := loadPracticeStore(path)
+	assert.NoError(t, err)
+	assert.Equal(t, store.cards, reloaded.cards, "expected the practice progress to be saved")
+
+	due := reloaded.selectDue("quiz", questions, 10, now.Add(time.Hour))
+	texts := make([]string, 0)
+	for _, q := range due {
+		texts = append(texts, q.question)
+	}
+	assert.Equal(t, []string{"3+3", "2+2"}, texts, "expected the correctly answered question to be scheduled for later")
+	assert.Equal(t, 1, len(reloaded.selectDue("quiz", questions, 1, now)), "expected the selection to be limited to the practice size")
+
+	assert.Equal(t, now.Add(24*time.Hour), reloaded.nextDue("quiz", []quizQuestion{questions[0]}))
+}
+
+func Test_practiceKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "quiz.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("1+1,2\n"), 0o644))
+	key := practiceKey(path)
+
+	// Editing the quiz file keeps its progress, as the cards are kept by the file's path rather than its content
+	assert.NoError(t, os.WriteFile(path, []byte("1+1,2\n2+2,4\n"), 0o644))
+	assert.Equal(t, key, practiceKey(path))
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	assert.Equal(t, key, practiceKey("quiz.csv"), "expected a relative path to have the same key")
+	assert.Equal(t, "https://example.com/quiz.csv", practiceKey("https://example.com/quiz.csv"))
+}
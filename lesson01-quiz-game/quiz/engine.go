@@ -0,0 +1,323 @@
+// Package quiz runs a timed quiz: it asks each question on an io.Writer, reads the answers from an io.Reader and
+// enforces the time limits using a Clock, so that the whole flow can be tested without a terminal or real time.
+package quiz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// Question is a single question which can be asked by the engine.
+type Question interface {
+	// Prompt returns the text used to ask the question, where number is its position in the quiz starting from 0
+	Prompt(number int) string
+	// IsCorrect reports whether answer is a correct answer to the question
+	IsCorrect(answer string) bool
+	// Limit is the time allowed to answer the question, or zero to use the quiz wide limit
+	Limit() time.Duration
+}
+
+// Outcome is the result of asking a single question.
+type Outcome int
+
+const (
+	Correct Outcome = iota
+	Incorrect
+	TimedOut
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Correct:
+		return "correct"
+	case Incorrect:
+		return "incorrect"
+	case TimedOut:
+		return "timed out"
+	default:
+		return "unknown"
+	}
+}
+
+// Answer is the answer given to a single question.
+type Answer[Q Question] struct {
+	Number   int
+	Question Q
+	Given    string
+	Outcome  Outcome
+	Duration time.Duration
+}
+
+// Order picks which question to ask next, for quizzes where the order depends on the answers given so far.
+type Order[Q Question] interface {
+	// Next returns the index of the question in remaining to ask next
+	Next(remaining []Q) int
+	// Record is called with the outcome of each question once it has been answered
+	Record(o Outcome)
+}
+
+// Status is how a quiz run finished.
+type Status int
+
+const (
+	// Completed means every question was asked
+	Completed Status = iota
+	// OutOfTime means the time limit for the whole quiz expired first
+	OutOfTime
+	// Paused means a value was received on the engine's Pause channel
+	Paused
+)
+
+// Result is what happened during a quiz run.
+type Result[Q Question] struct {
+	Status Status
+	// Questions are in the order they were asked, followed by any questions which were not asked
+	Questions []Q
+	// Answers are the answers given during this run, not including any given before Start
+	Answers []Answer[Q]
+	// Elapsed is how long the run lasted
+	Elapsed time.Duration
+}
+
+// Engine asks Questions from Start onwards, until they have all been asked or TimeLimit expires.
+type Engine[Q Question] struct {
+	In    io.Reader
+	Out   io.Writer
+	Clock Clock
+
+	Questions []Q
+	// Start is the index of the first question to ask, so that a paused quiz can be resumed
+	Start int
+	// TimeLimit is the time allowed for the whole quiz
+	TimeLimit time.Duration
+	// QuestionLimit is the time allowed for each question unless it sets its own limit, or zero for no limit
+	QuestionLimit time.Duration
+	// Order picks the next question, or the questions are asked in order when it is nil
+	Order Order[Q]
+	// Pause stops the quiz straight away when it receives a value. The question being asked is not answered.
+	Pause <-chan os.Signal
+}
+
+// Run asks the questions and returns once the quiz has completed, timed out or been paused.
+func (e *Engine[Q]) Run() Result[Q] {
+	clock := e.Clock
+	if clock == nil {
+		clock = RealClock
+	}
+
+	resultsChannel := make(chan asked[Q])
+	completedChannel := make(chan bool)
+	done := make(chan struct{})
+	defer close(done)
+
+	// askQuestions moves questions into the order they are asked on its own copy, and the same moves are made here
+	// as each answer arrives
+	result := Result[Q]{Questions: append([]Q(nil), e.Questions...)}
+	go e.askQuestions(clock, resultsChannel, completedChannel, done, append([]Q(nil), e.Questions...))
+
+	startedAt := clock.Now()
+	timeout := clock.NewTimer(e.TimeLimit)
+	defer timeout.Stop()
+
+	for {
+		select {
+		// Time has run out before all the questions have been answered
+		case <-timeout.C():
+			result.Status = OutOfTime
+
+		// All the questions have been answered
+		case <-completedChannel:
+			result.Status = Completed
+
+		// The quiz has been paused
+		case <-e.Pause:
+			result.Status = Paused
+
+		// The answer to a single question has been received
+		case a, ok := <-resultsChannel:
+			// ok indicates that it received an event rather than a zero value caused by the channel closing
+			if ok {
+				result.Answers = append(result.Answers, a.answer)
+				moveToFront(result.Questions[a.answer.Number:], a.from-a.answer.Number)
+				continue
+			}
+			// Stop selecting on the closed channel whilst waiting for the completion event
+			resultsChannel = nil
+			continue
+		}
+
+		result.Elapsed = clock.Now().Sub(startedAt)
+		return result
+	}
+}
+
+// askQuestions iterates through the questions from Start onwards and calls Ask for each one.
+// The answer to each question is sent to the rc channel (to be collected by Run), and when all questions have been
+// processed an event is sent to the cc channel to indicate completion. done is closed once Run has returned.
+func (e *Engine[Q]) askQuestions(clock Clock, rc chan asked[Q], cc chan bool, done chan struct{}, questions []Q) {
+	answers := ReadLines(e.In)
+
+	for i := e.Start; i < len(questions); i++ {
+		from := i
+		if e.Order != nil {
+			from += e.Order.Next(questions[i:])
+			moveToFront(questions[i:], from-i)
+		}
+		question := questions[i]
+
+		limit := e.QuestionLimit
+		if question.Limit() > 0 {
+			limit = question.Limit()
+		}
+
+		answer := Ask(e.Out, clock, question, i, answers, limit)
+		if e.Order != nil {
+			e.Order.Record(answer.Outcome)
+		}
+
+		// Send answers via the channel to be collected by Run
+		select {
+		case rc <- asked[Q]{answer: answer, from: from}:
+		case <-done:
+			return
+		}
+	}
+	close(rc)
+
+	// Indicate we have processed all the quiz questions
+	select {
+	case cc <- true:
+	case <-done:
+	}
+}
+
+// asked is sent from askQuestions to Run for every question which is asked. from is where the question was before
+// it was moved to the position it was asked in, so that Run can keep its questions in the same order.
+type asked[Q Question] struct {
+	answer Answer[Q]
+	from   int
+}
+
+// moveToFront moves questions[i] to the start of questions, shifting the questions before it along by one.
+func moveToFront[Q any](questions []Q, i int) {
+	q := questions[i]
+	copy(questions[1:i+1], questions[:i])
+	questions[0] = q
+}
+
+// ReadLines reads lines from reader in the background and sends them to the returned channel, which is closed
+// when there is no more input. Reading in the background allows a question to time out whilst waiting for input.
+func ReadLines(reader io.Reader) <-chan string {
+	lines := make(chan string)
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("problem reading input: %v", err)
+		}
+	}()
+
+	return lines
+}
+
+// lateAnswerGrace is how long input is ignored for after a question times out, so that an answer which was being
+// typed when the time ran out isn't taken as the answer to the next question.
+const lateAnswerGrace = time.Second
+
+// Ask asks a question by writing it to out, and inspects the next line of input from answers.
+// When limit is greater than zero a countdown is shown next to the prompt, and the question times out
+// if no answer is given before the limit expires. Any input received within lateAnswerGrace of timing out is discarded.
+func Ask[Q Question](out io.Writer, clock Clock, question Q, number int, answers <-chan string, limit time.Duration) Answer[Q] {
+	answer := Answer[Q]{Number: number, Question: question}
+	prompt := question.Prompt(number)
+
+	// Nil channels block forever, so without a limit the question only finishes when an answer is given
+	var deadline, tick <-chan time.Time
+	if limit > 0 {
+		deadlineTimer := clock.NewTimer(limit)
+		defer deadlineTimer.Stop()
+		ticker := clock.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		deadline, tick = deadlineTimer.C(), ticker.C()
+		prompt = withCountdown(prompt, limit)
+	}
+	_, _ = fmt.Fprint(out, prompt)
+
+	start := clock.Now()
+	remaining := limit
+	for {
+		select {
+		case given, ok := <-answers:
+			answer.Duration = clock.Now().Sub(start)
+			if !ok {
+				log.Printf("problem reading input: no more input")
+				answer.Outcome = Incorrect
+				return answer
+			}
+
+			answer.Given = given
+			answer.Outcome = Incorrect
+			if question.IsCorrect(given) {
+				answer.Outcome = Correct
+			}
+			return answer
+
+		case <-tick:
+			remaining -= time.Second
+			// Redraw the countdown at the start of the line without moving the cursor away from the user's input
+			_, _ = fmt.Fprintf(out, "\x1b7\r%s\x1b8", countdown(remaining))
+
+		case <-deadline:
+			_, _ = fmt.Fprintf(out, "\nTime's up!\n")
+			answer.Duration = clock.Now().Sub(start)
+			answer.Outcome = TimedOut
+			discardLateAnswers(clock, answers)
+			return answer
+		}
+	}
+}
+
+// discardLateAnswers reads and discards answers until lateAnswerGrace has passed, or there is no more input.
+func discardLateAnswers(clock Clock, answers <-chan string) {
+	grace := clock.NewTimer(lateAnswerGrace)
+	defer grace.Stop()
+
+	for {
+		select {
+		case _, ok := <-answers:
+			if !ok {
+				return
+			}
+		case <-grace.C():
+			return
+		}
+	}
+}
+
+// withCountdown adds the countdown to the start of the last line of the prompt, which is where the cursor sits.
+func withCountdown(prompt string, remaining time.Duration) string {
+	i := strings.LastIndex(prompt, "\n") + 1
+
+	return prompt[:i] + countdown(remaining) + prompt[i:]
+}
+
+// countdown returns the remaining time in whole seconds, padded to a fixed width so it can be redrawn in place.
+func countdown(remaining time.Duration) string {
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return fmt.Sprintf("[%3ds] ", int(math.Ceil(remaining.Seconds())))
+}
@@ -0,0 +1,342 @@
+package quiz
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testQuestion is a minimal Question for testing the engine.
+type testQuestion struct {
+	text   string
+	answer string
+	limit  time.Duration
+}
+
+func (q testQuestion) Prompt(number int) string {
+	return fmt.Sprintf("Question #%d: %s = ", number+1, q.text)
+}
+
+func (q testQuestion) IsCorrect(answer string) bool {
+	return strings.TrimSpace(answer) == q.answer
+}
+
+func (q testQuestion) Limit() time.Duration {
+	return q.limit
+}
+
+// fakeClock is a Clock which only moves when Advance is called, so that the order in which timers expire is fixed.
+type fakeClock struct {
+	mu      sync.Mutex
+	created *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+	count   int
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.created = sync.NewCond(&c.mu)
+
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	return c.add(d, 0)
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return fakeTicker{c.add(d, d)}
+}
+
+func (c *fakeClock) add(d, period time.Duration) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d), period: period}
+	c.timers = append(c.timers, t)
+	c.count++
+	c.created.Broadcast()
+
+	return t
+}
+
+// waitForTimers blocks until n timers and tickers have been created in total, which is how the tests know which
+// question the engine has got to.
+func (c *fakeClock) waitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.count < n {
+		c.created.Wait()
+	}
+}
+
+// Advance moves the clock forward by d, firing any timers which expire in the order that they expire.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+	for {
+		due := make([]*fakeTimer, 0)
+		for _, t := range c.timers {
+			if !t.stopped && !t.at.After(target) {
+				due = append(due, t)
+			}
+		}
+		if len(due) == 0 {
+			break
+		}
+		sort.Slice(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+
+		t := due[0]
+		c.now = t.at
+		// Like the time package, a tick is dropped when the previous one hasn't been received yet
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			t.at = t.at.Add(t.period)
+		} else {
+			t.stopped = true
+		}
+	}
+	c.now = target
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	c       chan time.Time
+	at      time.Time
+	period  time.Duration
+	stopped bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := !t.stopped
+	t.stopped = true
+
+	return active
+}
+
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+// lastFirst is an Order which always asks the last remaining question.
+type lastFirst struct {
+	outcomes []Outcome
+}
+
+func (o *lastFirst) Next(remaining []testQuestion) int {
+	return len(remaining) - 1
+}
+
+func (o *lastFirst) Record(outcome Outcome) {
+	o.outcomes = append(o.outcomes, outcome)
+}
+
+var testQuestions = []testQuestion{{text: "1+1", answer: "2"}, {text: "2+2", answer: "4"}, {text: "3+3", answer: "6"}}
+
+// runInBackground starts the engine and returns a channel which receives its result.
+func runInBackground(e *Engine[testQuestion]) <-chan Result[testQuestion] {
+	done := make(chan Result[testQuestion], 1)
+	go func() {
+		done <- e.Run()
+	}()
+
+	return done
+}
+
+func outcomes(answers []Answer[testQuestion]) []Outcome {
+	o := make([]Outcome, 0, len(answers))
+	for _, a := range answers {
+		o = append(o, a.Outcome)
+	}
+
+	return o
+}
+
+func Test_EngineCompleted(t *testing.T) {
+	var out strings.Builder
+	e := &Engine[testQuestion]{In: strings.NewReader("2\n5\n6\n"), Out: &out, Clock: newFakeClock(), Questions: testQuestions, TimeLimit: 30 * time.Second}
+
+	result := e.Run()
+
+	assert.Equal(t, Completed, result.Status)
+	assert.Equal(t, []Outcome{Correct, Incorrect, Correct}, outcomes(result.Answers))
+	assert.Equal(t, "Question #1: 1+1 = Question #2: 2+2 = Question #3: 3+3 = ", out.String())
+	assert.Equal(t, time.Duration(0), result.Elapsed, "expected no time to pass on the fake clock")
+}
+
+func Test_EngineOutOfTime(t *testing.T) {
+	clock := newFakeClock()
+	in, answers := io.Pipe()
+	defer answers.Close()
+
+	// The question limit is longer than the quiz, so the quiz timer is the one which expires
+	e := &Engine[testQuestion]{In: in, Out: io.Discard, Clock: clock, Questions: testQuestions, TimeLimit: 10 * time.Second, QuestionLimit: time.Minute}
+	done := runInBackground(e)
+
+	// The quiz timer, then a deadline and a countdown ticker for the first question
+	clock.waitForTimers(3)
+	clock.Advance(4 * time.Second)
+	_, _ = io.WriteString(answers, "2\n")
+
+	// The second question has been asked, so the answer to the first has been received by Run
+	clock.waitForTimers(5)
+	clock.Advance(6 * time.Second)
+
+	result := <-done
+	assert.Equal(t, OutOfTime, result.Status)
+	assert.Equal(t, []Outcome{Correct}, outcomes(result.Answers), "expected the answer given before the timeout to be kept")
+	assert.Equal(t, 4*time.Second, result.Answers[0].Duration)
+	assert.Equal(t, 10*time.Second, result.Elapsed)
+}
+
+func Test_EngineQuestionTimesOut(t *testing.T) {
+	clock := newFakeClock()
+	in, answers := io.Pipe()
+	defer answers.Close()
+
+	questions := []testQuestion{{text: "1+1", answer: "2", limit: 5 * time.Second}, {text: "2+2", answer: "4"}}
+	e := &Engine[testQuestion]{In: in, Out: io.Discard, Clock: clock, Questions: questions, TimeLimit: time.Minute, QuestionLimit: time.Minute}
+	done := runInBackground(e)
+
+	// The first question sets a shorter limit than the quiz wide one, and moves on to the second question once it
+	// expires and the grace period for late answers has passed
+	clock.waitForTimers(3)
+	clock.Advance(5 * time.Second)
+	clock.waitForTimers(4)
+	clock.Advance(lateAnswerGrace)
+	clock.waitForTimers(6)
+	_, _ = io.WriteString(answers, "4\n")
+
+	result := <-done
+	assert.Equal(t, Completed, result.Status)
+	assert.Equal(t, []Outcome{TimedOut, Correct}, outcomes(result.Answers))
+	assert.Equal(t, "", result.Answers[0].Given, "expected no answer to be recorded for the question which timed out")
+	assert.Equal(t, 5*time.Second, result.Answers[0].Duration)
+}
+
+func Test_EnginePaused(t *testing.T) {
+	clock := newFakeClock()
+	in, answers := io.Pipe()
+	defer answers.Close()
+
+	pause := make(chan os.Signal, 1)
+	e := &Engine[testQuestion]{In: in, Out: io.Discard, Clock: clock, Questions: testQuestions, TimeLimit: time.Minute, QuestionLimit: time.Minute, Pause: pause}
+	done := runInBackground(e)
+
+	clock.waitForTimers(3)
+	_, _ = io.WriteString(answers, "2\n")
+	clock.waitForTimers(5)
+	clock.Advance(3 * time.Second)
+	pause <- os.Interrupt
+
+	result := <-done
+	assert.Equal(t, Paused, result.Status)
+	assert.Equal(t, []Outcome{Correct}, outcomes(result.Answers), "expected the question being asked when paused to be discarded")
+	assert.Equal(t, 3*time.Second, result.Elapsed)
+}
+
+func Test_EngineOrder(t *testing.T) {
+	order := &lastFirst{}
+	e := &Engine[testQuestion]{In: strings.NewReader("6\n5\n"), Out: io.Discard, Clock: newFakeClock(), Questions: testQuestions, TimeLimit: time.Minute, Order: order, Start: 1}
+
+	result := e.Run()
+
+	assert.Equal(t, Completed, result.Status)
+	assert.Equal(t, []Outcome{Correct, Incorrect}, order.outcomes, "expected the order to be told the outcome of each question")
+	assert.Equal(t, []testQuestion{testQuestions[0], testQuestions[2], testQuestions[1]}, result.Questions, "expected the questions in the order they were asked")
+	assert.Equal(t, []int{1, 2}, []int{result.Answers[0].Number, result.Answers[1].Number})
+	assert.Equal(t, testQuestions[2], result.Answers[0].Question)
+}
+
+func Test_AskTimesOut(t *testing.T) {
+	clock := newFakeClock()
+	// No answer is ever sent, so the question can only finish by timing out
+	answers := make(chan string)
+
+	done := make(chan Answer[testQuestion], 1)
+	go func() {
+		done <- Ask(io.Discard, clock, testQuestions[0], 0, answers, 10*time.Second)
+	}()
+	clock.waitForTimers(2)
+	clock.Advance(10 * time.Second)
+	clock.waitForTimers(3)
+	clock.Advance(lateAnswerGrace)
+
+	answer := <-done
+	assert.Equal(t, TimedOut, answer.Outcome, "expected the question to time out")
+	assert.Equal(t, "", answer.Given, "expected no answer to be recorded")
+}
+
+func Test_AskDiscardsLateAnswer(t *testing.T) {
+	clock := newFakeClock()
+	// The same answers are shared by every question, as they are in the engine
+	answers := make(chan string)
+
+	done := make(chan Answer[testQuestion], 1)
+	go func() {
+		done <- Ask(io.Discard, clock, testQuestions[0], 0, answers, 10*time.Second)
+	}()
+	clock.waitForTimers(2)
+	clock.Advance(10 * time.Second)
+
+	// The answer to the first question is sent just after it timed out, during the grace period
+	clock.waitForTimers(3)
+	answers <- "2"
+	clock.Advance(lateAnswerGrace)
+	assert.Equal(t, TimedOut, (<-done).Outcome)
+
+	go func() {
+		done <- Ask(io.Discard, clock, testQuestions[1], 1, answers, 10*time.Second)
+	}()
+	answers <- "4"
+
+	answer := <-done
+	assert.Equal(t, Correct, answer.Outcome, "expected the late answer not to be taken as the answer to the next question")
+	assert.Equal(t, "4", answer.Given)
+}
+
+func Test_AskWithinLimit(t *testing.T) {
+	answer := Ask(io.Discard, newFakeClock(), testQuestions[0], 0, ReadLines(strings.NewReader("2\n")), time.Minute)
+
+	assert.Equal(t, Correct, answer.Outcome, "expected the answer to be accepted before the limit expired")
+	assert.Equal(t, "2", answer.Given)
+	assert.Equal(t, 0, answer.Number)
+}
+
+func Test_withCountdown(t *testing.T) {
+	assert.Equal(t, "[ 10s] Question #1: 1+1 = ", withCountdown("Question #1: 1+1 = ", 10*time.Second))
+	assert.Equal(t, "Question #1: colour?\n  a) red\n[  2s] Choose a-a: ", withCountdown("Question #1: colour?\n  a) red\nChoose a-a: ", 1500*time.Millisecond))
+	assert.Equal(t, "[  0s] ", countdown(-time.Second), "expected the countdown to stop at zero")
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const remoteCacheDirName = "banks"
+
+// remoteTimeout is how long to wait for a remote quiz file before falling back to the cached copy.
+const remoteTimeout = 30 * time.Second
+
+// isRemote reports whether a quiz file is a http(s) URL rather than a local path.
+func isRemote(quizFile string) bool {
+	lower := strings.ToLower(quizFile)
+
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// resolveQuizFile returns a local path for the quiz file, fetching it into the cache first if it is a URL.
+// When pin is set the file must have that SHA-256 hash (as hex), to check that it hasn't been tampered with.
+func resolveQuizFile(quizFile, pin string) (string, error) {
+	if !isRemote(quizFile) {
+		if pin != "" {
+			if err := verifyPin(quizFile, pin); err != nil {
+				return "", err
+			}
+		}
+		return quizFile, nil
+	}
+
+	cache, err := newRemoteCache("")
+	if err != nil {
+		return "", err
+	}
+
+	return cache.fetch(quizFile, pin)
+}
+
+// remoteCache keeps a copy of every quiz file fetched from a URL, so they can be revalidated cheaply with ETag and
+// If-Modified-Since, and used offline when the server can't be reached.
+type remoteCache struct {
+	dir    string
+	client *http.Client
+}
+
+// cachedQuizFile is the metadata saved alongside each cached quiz file.
+type cachedQuizFile struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// newRemoteCache returns a cache which stores files in dir, or in the user's cache dir when dir is empty.
+func newRemoteCache(dir string) (remoteCache, error) {
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return remoteCache{}, fmt.Errorf("finding the user cache dir: %v", err)
+		}
+		dir = filepath.Join(cacheDir, configDirName, remoteCacheDirName)
+	}
+
+	return remoteCache{dir: dir, client: &http.Client{Timeout: remoteTimeout}}, nil
+}
+
+// paths returns where the quiz file at rawURL and its metadata are cached. The file keeps the extension from the URL
+// so that its format can still be detected.
+func (c remoteCache) paths(rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid quiz file URL '%s': %v", rawURL, err)
+	}
+
+	sum := sha256.Sum256([]byte(rawURL))
+	name := hex.EncodeToString(sum[:])
+
+	return filepath.Join(c.dir, name+path.Ext(u.Path)), filepath.Join(c.dir, name+".json"), nil
+}
+
+// fetch downloads the quiz file at rawURL into the cache, unless the cached copy is still up to date, and returns the
+// path of the cached copy. If the server can't be reached or returns a server error the cached copy is used, as long
+// as there is one. The cached copy is only replaced by a successful download.
+func (c remoteCache) fetch(rawURL, pin string) (string, error) {
+	filePath, metaPath, err := c.paths(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	var meta cachedQuizFile
+	cached := false
+	if b, err := os.ReadFile(metaPath); err == nil {
+		cached = json.Unmarshal(b, &meta) == nil && fileExists(filePath)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid quiz file URL '%s': %v", rawURL, err)
+	}
+	if cached {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if !cached {
+			return "", fmt.Errorf("fetching quiz file '%s': %v", rawURL, err)
+		}
+		log.Printf("Unable to fetch %s, using the copy cached at %s: %v", rawURL, meta.FetchedAt.Format(time.RFC822), err)
+		return verifiedPath(rawURL, filePath, pin)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		return verifiedPath(rawURL, filePath, pin)
+	// A server error is treated like the server being unreachable, rather than a sign that the file has gone
+	case resp.StatusCode >= http.StatusInternalServerError && cached:
+		log.Printf("Unable to fetch %s, using the copy cached at %s: unexpected status %s", rawURL, meta.FetchedAt.Format(time.RFC822), resp.Status)
+		return verifiedPath(rawURL, filePath, pin)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("fetching quiz file '%s': unexpected status %s", rawURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("fetching quiz file '%s': %v", rawURL, err)
+	}
+	// Check the pin before caching, so that a tampered file can't replace a good copy
+	if err = checkPin(rawURL, body, pin); err != nil {
+		return "", err
+	}
+
+	meta = cachedQuizFile{URL: rawURL, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), FetchedAt: time.Now()}
+	if err = c.save(filePath, metaPath, body, meta); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// verifiedPath returns the path of the cached copy of rawURL once it has been checked against the pin.
+func verifiedPath(rawURL, filePath, pin string) (string, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("reading cached quiz file '%s': %v", filePath, err)
+	}
+	if err = checkPin(rawURL, b, pin); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// save writes the quiz file and then its metadata to the cache.
+func (c remoteCache) save(filePath, metaPath string, body []byte, meta cachedQuizFile) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("creating quiz cache dir: %v", err)
+	}
+
+	// Write to a temporary file first so that an interrupted download can't leave a partial quiz file in the cache
+	tmp := filePath + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return fmt.Errorf("writing cached quiz file '%s': %v", tmp, err)
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		return fmt.Errorf("replacing cached quiz file '%s': %v", filePath, err)
+	}
+
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding quiz cache metadata: %v", err)
+	}
+	if err = os.WriteFile(metaPath, b, 0o644); err != nil {
+		return fmt.Errorf("writing quiz cache metadata '%s': %v", metaPath, err)
+	}
+
+	return nil
+}
+
+// verifyPin checks that the file at path has the SHA-256 hash pin. An empty pin always passes.
+func verifyPin(path, pin string) error {
+	if pin == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading quiz file '%s': %v", path, err)
+	}
+
+	return checkPin(path, b, pin)
+}
+
+// checkPin checks that b has the SHA-256 hash pin. An empty pin always passes.
+func checkPin(name string, b []byte, pin string) error {
+	if pin == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256(b)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, strings.TrimSpace(pin)) {
+		return fmt.Errorf("quiz file '%s' has SHA-256 %s, which doesn't match the pinned %s", name, got, pin)
+	}
+
+	return nil
+}
+
+// fileExists reports whether there is a file at path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const remoteQuiz = "question,answer\n5+5,10\n"
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func Test_remoteCacheETag(t *testing.T) {
+	body := remoteQuiz
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + sha256Hex(body) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cache, err := newRemoteCache(t.TempDir())
+	assert.NoError(t, err)
+
+	path, err := cache.fetch(server.URL+"/banks/maths.csv", "")
+	assert.NoError(t, err)
+	assert.Equal(t, ".csv", filepath.Ext(path), "expected the cached copy to keep the extension so the format can be detected")
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteQuiz, string(b))
+
+	_, err = cache.fetch(server.URL+"/banks/maths.csv", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, downloads, "expected the cached copy to be revalidated with the ETag rather than downloaded again")
+
+	body = "question,answer\n6+6,12\n"
+	path, err = cache.fetch(server.URL+"/banks/maths.csv", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, downloads, "expected the file to be downloaded again once it has changed")
+	b, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(b))
+}
+
+func Test_remoteCacheLastModified(t *testing.T) {
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ifModifiedSince string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifModifiedSince = r.Header.Get("If-Modified-Since")
+		http.ServeContent(w, r, "maths.csv", modified, strings.NewReader(remoteQuiz))
+	}))
+	defer server.Close()
+
+	cache, err := newRemoteCache(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = cache.fetch(server.URL+"/maths.csv", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", ifModifiedSince, "expected no If-Modified-Since before anything is cached")
+
+	path, err := cache.fetch(server.URL+"/maths.csv", "")
+	assert.NoError(t, err)
+	assert.Equal(t, modified.Format(http.TimeFormat), ifModifiedSince, "expected the cached copy to be revalidated with its Last-Modified time")
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteQuiz, string(b), "expected the cached copy to be used when it hasn't been modified")
+}
+
+func Test_remoteCacheOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(remoteQuiz))
+	}))
+	url := server.URL + "/maths.csv"
+
+	cache, err := newRemoteCache(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = cache.fetch(server.URL+"/other.csv", "")
+	assert.NoError(t, err)
+	cached, err := cache.fetch(url, "")
+	assert.NoError(t, err)
+
+	server.Close()
+	path, err := cache.fetch(url, "")
+	assert.NoError(t, err, "expected the cached copy to be used when the server can't be reached")
+	assert.Equal(t, cached, path)
+
+	_, err = cache.fetch(server.URL+"/never-fetched.csv", "")
+	assert.Error(t, err, "expected an error when the server can't be reached and nothing is cached")
+}
+
+func Test_remoteCacheServerError(t *testing.T) {
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(remoteQuiz))
+	}))
+	defer server.Close()
+	url := server.URL + "/maths.csv"
+
+	cache, err := newRemoteCache(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = cache.fetch(server.URL+"/other.csv", "")
+	assert.NoError(t, err)
+	cached, err := cache.fetch(url, "")
+	assert.NoError(t, err)
+
+	failing = true
+	path, err := cache.fetch(url, sha256Hex(remoteQuiz))
+	assert.NoError(t, err, "expected the cached copy to be used when the server returns an error")
+	assert.Equal(t, cached, path)
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteQuiz, string(b), "expected the error response not to replace the cached copy")
+
+	_, err = cache.fetch(server.URL+"/never-fetched.csv", "")
+	assert.Error(t, err, "expected an error when the server returns an error and nothing is cached")
+}
+
+func Test_remoteCachePin(t *testing.T) {
+	body := remoteQuiz
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cache, err := newRemoteCache(t.TempDir())
+	assert.NoError(t, err)
+	pin := sha256Hex(remoteQuiz)
+
+	path, err := cache.fetch(server.URL+"/maths.csv", pin)
+	assert.NoError(t, err)
+
+	body = "question,answer\n5+5,11\n"
+	_, err = cache.fetch(server.URL+"/maths.csv", pin)
+	assert.Error(t, err, "expected an error when the file doesn't match the pin")
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteQuiz, string(b), "expected a file which doesn't match the pin not to replace the cached copy")
+
+	_, err = cache.fetch(server.URL+"/missing.csv", "")
+	assert.Error(t, err, "expected an error for a missing file")
+
+	assert.NoError(t, verifyPin("./testdata/valid.csv", ""), "expected no check without a pin")
+	assert.Error(t, verifyPin("./testdata/valid.csv", pin))
+}
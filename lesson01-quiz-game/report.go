@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"quiz-game/quiz"
+)
+
+// answerRecord is the answer to a single question, built from each quiz.Answer which quiz.Engine or quiz.Ask returns.
+type answerRecord struct {
+	number   int
+	question quizQuestion
+	given    string
+	outcome  outcome
+	duration time.Duration
+}
+
+// newAnswerRecord converts an answer from the quiz engine into an answerRecord.
+func newAnswerRecord(a quiz.Answer[quizQuestion]) answerRecord {
+	return answerRecord{number: a.Number, question: a.Question, given: a.Given, outcome: a.Outcome, duration: a.Duration}
+}
+
+// quizReport is the per-question breakdown of a quiz run, which can be printed or exported with -report.
+type quizReport struct {
+	QuizFile  string    `json:"quiz_file"`
+	StartedAt time.Time `json:"started_at"`
+	Correct   int       `json:"correct"`
+	Total     int       `json:"total"`
+	// Score weights each correct answer by the difficulty of the question, out of MaxScore
+	Score     int           `json:"score"`
+	MaxScore  int           `json:"max_score"`
+	Questions []reportRow   `json:"questions"`
+	Topics    []topicResult `json:"topics,omitempty"`
+}
+
+// reportRow is the result of a single question within a quizReport.
+type reportRow struct {
+	Number      int      `json:"number"`
+	Question    string   `json:"question"`
+	Given       string   `json:"given"`
+	Expected    string   `json:"expected"`
+	Result      string   `json:"result"`
+	TimeTakenMs int64    `json:"time_taken_ms"`
+	Difficulty  string   `json:"difficulty,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Points      int      `json:"points"`
+}
+
+// buildReport combines the answer records received so far with the quiz questions. Questions which were never
+// asked (because the quiz timed out) are included as unanswered.
+func buildReport(quizFile string, startedAt time.Time, questions []quizQuestion, records []answerRecord) quizReport {
+	report := quizReport{QuizFile: quizFile, StartedAt: startedAt, Total: len(questions), Questions: make([]reportRow, 0, len(questions))}
+
+	asked := make(map[int]answerRecord)
+	for _, r := range records {
+		asked[r.number] = r
+	}
+
+	for i, q := range questions {
+		row := reportRow{Number: i + 1, Question: q.question, Expected: q.answer, Result: "unanswered", Difficulty: q.difficulty.String(), Tags: q.tags}
+		report.MaxScore += q.difficulty.points()
+
+		if r, ok := asked[i]; ok {
+			row.Given = r.given
+			row.Result = r.outcome.String()
+			row.TimeTakenMs = r.duration.Milliseconds()
+
+			if r.outcome == answeredCorrectly {
+				report.Correct++
+				row.Points = q.difficulty.points()
+				report.Score += row.Points
+			}
+		}
+		report.Questions = append(report.Questions, row)
+	}
+	report.Topics = topicBreakdown(report.Questions)
+
+	return report
+}
+
+// printReport prints a table with the result of every question in the quiz.
+func printReport(report quizReport) {
+	fmt.Printf("\n\nResults:\n")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "#\tQuestion\tGiven\tExpected\tResult\tTime")
+	for _, row := range report.Questions {
+		taken := "-"
+		if row.Result != "unanswered" {
+			taken = (time.Duration(row.TimeTakenMs) * time.Millisecond).String()
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", row.Number, row.Question, row.Given, row.Expected, row.Result, taken)
+	}
+	_ = w.Flush()
+
+	if report.rated() {
+		fmt.Printf("\nScore: %d out of %d points (easy 1, medium 2, hard 3)\n", report.Score, report.MaxScore)
+	}
+
+	if len(report.Topics) == 0 {
+		return
+	}
+	fmt.Printf("\nBy topic:\n")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Topic\tCorrect\tTotal")
+	for _, t := range report.Topics {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\n", t.Topic, t.Correct, t.Total)
+	}
+	_ = w.Flush()
+}
+
+// rated reports whether any of the questions have a difficulty, in which case the weighted score is shown.
+func (r quizReport) rated() bool {
+	for _, row := range r.Questions {
+		if row.Difficulty != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateReportPath checks that a report can be written in the format implied by the file extension of path.
+func validateReportPath(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".csv":
+		return nil
+	default:
+		return fmt.Errorf("unsupported report file '%s', expected a .json or .csv extension", path)
+	}
+}
+
+// writeReport writes the report to path as either JSON or CSV depending on the file extension.
+func writeReport(path string, report quizReport) error {
+	if err := validateReportPath(path); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating report file '%s': %v", path, err)
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = writeJSONReport(f, report)
+	} else {
+		err = writeCSVReport(f, report)
+	}
+	if err != nil {
+		return fmt.Errorf("writing report file '%s': %v", path, err)
+	}
+
+	return f.Close()
+}
+
+// writeJSONReport writes the whole report, including the summary, as an indented JSON document.
+func writeJSONReport(w io.Writer, report quizReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
+
+// writeCSVReport writes one row per question. The start time and quiz file are repeated on every row so that
+// reports from several runs can be concatenated and still be analysed.
+func writeCSVReport(w io.Writer, report quizReport) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"quiz_file", "started_at", "number", "question", "given", "expected", "result", "time_taken_ms"})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range report.Questions {
+		err = writer.Write([]string{
+			report.QuizFile,
+			report.StartedAt.Format(time.RFC3339),
+			strconv.Itoa(row.Number),
+			row.Question,
+			row.Given,
+			row.Expected,
+			row.Result,
+			strconv.FormatInt(row.TimeTakenMs, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
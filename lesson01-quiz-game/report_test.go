@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport() quizReport {
+	questions := []quizQuestion{{question: "1+1", answer: "2"}, {question: "2+2", answer: "4"}, {question: "3+3", answer: "6"}}
+	records := []answerRecord{
+		{number: 0, question: questions[0], given: "2", outcome: answeredCorrectly, duration: 1500 * time.Millisecond},
+		{number: 1, question: questions[1], given: "", outcome: timedOut, duration: 5 * time.Second},
+	}
+
+	return buildReport("problems.csv", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), questions, records)
+}
+
+func Test_buildReport(t *testing.T) {
+	report := testReport()
+
+	assert.Equal(t, 1, report.Correct)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, []reportRow{
+		{Number: 1, Question: "1+1", Given: "2", Expected: "2", Result: "correct", TimeTakenMs: 1500, Points: 2},
+		{Number: 2, Question: "2+2", Given: "", Expected: "4", Result: "timed out", TimeTakenMs: 5000},
+		{Number: 3, Question: "3+3", Given: "", Expected: "6", Result: "unanswered", TimeTakenMs: 0},
+	}, report.Questions)
+}
+
+func Test_writeReport(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "report.json")
+	assert.NoError(t, writeReport(jsonPath, testReport()))
+
+	b, err := os.ReadFile(jsonPath)
+	assert.NoError(t, err)
+	var decoded quizReport
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, testReport(), decoded, "expected the JSON report to round trip")
+
+	csvPath := filepath.Join(dir, "report.csv")
+	assert.NoError(t, writeReport(csvPath, testReport()))
+
+	f, err := os.Open(csvPath)
+	assert.NoError(t, err)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(rows), "expected a header and one row per question")
+	assert.Equal(t, []string{"problems.csv", "2024-01-02T03:04:05Z", "2", "2+2", "", "4", "timed out", "5000"}, rows[2])
+
+	assert.Error(t, writeReport(filepath.Join(dir, "report.txt"), testReport()), "expected an error for an unsupported extension")
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"quiz-game/quiz"
+)
+
+// defaultRoundLimit is the time allowed for each round in serve mode when neither -question-limit nor the question
+// sets a limit. Without a limit a single idle player would hold up everybody else.
+const defaultRoundLimit = 30 * time.Second
+
+// joinTimeout is how long a connection has to send a name before it is dropped, so that an idle connection can't
+// hold on to a place in the quiz.
+const joinTimeout = time.Minute
+
+// player is a single connection to the quiz server.
+type player struct {
+	name  string
+	conn  net.Conn
+	score int
+	// order is when the player connected, which the players are kept in so that ties on the scoreboard are stable
+	order int
+
+	// answers receives each line sent by the player, and left is closed once they disconnect
+	answers chan string
+	left    chan struct{}
+}
+
+// readAnswers forwards lines from the player's connection to the answers channel until they disconnect.
+func (p *player) readAnswers() {
+	defer close(p.left)
+	defer close(p.answers)
+
+	scanner := bufio.NewScanner(p.conn)
+	for scanner.Scan() {
+		p.answers <- scanner.Text()
+	}
+}
+
+// connected reports whether the player is still connected.
+func (p *player) connected() bool {
+	select {
+	case <-p.left:
+		return false
+	default:
+		return true
+	}
+}
+
+// playerScore is a player's name and score, as shown on the scoreboard.
+type playerScore struct {
+	name  string
+	score int
+}
+
+// quizServer hosts one quiz over TCP. Every player is asked the same question at the same time and a scoreboard is
+// broadcast after each round. Players can join with any line based client e.g. 'nc localhost 9000'.
+type quizServer struct {
+	listener      net.Listener
+	questions     []quizQuestion
+	playerCount   int
+	questionLimit time.Duration
+
+	players []*player
+}
+
+// newQuizServer returns a server which waits on listener for playerCount players before starting the quiz.
+func newQuizServer(listener net.Listener, questions []quizQuestion, playerCount int, questionLimit time.Duration) *quizServer {
+	return &quizServer{
+		listener:      listener,
+		questions:     questions,
+		playerCount:   playerCount,
+		questionLimit: questionLimit,
+	}
+}
+
+// run waits for the players to join, asks every question and then returns the final scores.
+func (s *quizServer) run() ([]playerScore, error) {
+	if s.playerCount < 1 {
+		return nil, errors.New("at least one player is needed to start the quiz")
+	}
+
+	if err := s.waitForPlayers(); err != nil {
+		return nil, err
+	}
+	defer s.disconnectAll()
+
+	s.broadcast("\nAll players have joined, the quiz is starting!\n")
+
+	for i, question := range s.questions {
+		s.playRound(i, question)
+
+		if len(s.connectedPlayers()) == 0 {
+			log.Printf("All players have left, finishing the quiz early")
+			break
+		}
+		s.broadcast(fmt.Sprintf("\nScoreboard after round %d of %d:\n%s", i+1, len(s.questions), formatScoreboard(s.scores())))
+	}
+
+	scores := s.scores()
+	s.broadcast(fmt.Sprintf("\nFinal scores:\n%s", formatScoreboard(scores)))
+
+	return scores, nil
+}
+
+// waitForPlayers accepts connections until enough players have joined and given their name. Each connection is
+// asked for its name in the background, so a connection which never sends one doesn't stop anybody else joining.
+func (s *quizServer) waitForPlayers() error {
+	joined := make(chan *player)
+	started := make(chan struct{})
+	defer close(started)
+
+	acceptErr := make(chan error, 1)
+	go func() {
+		for order := 0; ; order++ {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				acceptErr <- err
+				return
+			}
+			go s.join(conn, order, joined, started)
+		}
+	}()
+
+	for len(s.players) < s.playerCount {
+		var p *player
+		select {
+		case p = <-joined:
+		case err := <-acceptErr:
+			return fmt.Errorf("accepting player connection: %v", err)
+		}
+
+		if p.name == "" {
+			p.name = fmt.Sprintf("player %d", len(s.players)+1)
+		}
+		i := sort.Search(len(s.players), func(i int) bool { return s.players[i].order > p.order })
+		s.players = append(s.players[:i], append([]*player{p}, s.players[i:]...)...)
+
+		log.Printf("%s joined from %s (%d/%d)", p.name, p.conn.RemoteAddr(), len(s.players), s.playerCount)
+		s.broadcast(fmt.Sprintf("%s has joined (%d/%d players)\n", p.name, len(s.players), s.playerCount))
+	}
+
+	return nil
+}
+
+// join asks a new connection for the player's name and then sends the player to joined, unless the quiz has already
+// started or they don't send a name within joinTimeout.
+func (s *quizServer) join(conn net.Conn, order int, joined chan<- *player, started <-chan struct{}) {
+	p := &player{conn: conn, order: order, answers: make(chan string), left: make(chan struct{})}
+	_ = conn.SetReadDeadline(time.Now().Add(joinTimeout))
+	go p.readAnswers()
+
+	s.write(p, "Welcome to the quiz! Enter your name: ")
+	name, ok := <-p.answers
+	if !ok {
+		log.Printf("Player from %s left before joining", conn.RemoteAddr())
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	p.name = strings.TrimSpace(name)
+
+	select {
+	case joined <- p:
+	case <-started:
+		s.write(p, "Sorry, the quiz has already started\n")
+		_ = conn.Close()
+	}
+}
+
+// playRound asks a question to every connected player at the same time, and waits until they have all answered
+// or the round's time limit expires.
+func (s *quizServer) playRound(number int, question quizQuestion) {
+	limit := s.questionLimit
+	if question.limit > 0 {
+		limit = question.limit
+	}
+	if limit <= 0 {
+		limit = defaultRoundLimit
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range s.connectedPlayers() {
+		wg.Add(1)
+		go func(p *player) {
+			defer wg.Done()
+
+			answer := quiz.Ask(p.conn, quiz.RealClock, question, number, p.answers, limit)
+			switch answer.Outcome {
+			case answeredCorrectly:
+				p.score++
+				s.write(p, "Correct!\n")
+			case answeredIncorrectly:
+				s.write(p, fmt.Sprintf("Wrong, the answer was %s\n", question.answer))
+			case timedOut:
+				s.write(p, fmt.Sprintf("The answer was %s\n", question.answer))
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// connectedPlayers returns the players who have not yet disconnected.
+func (s *quizServer) connectedPlayers() []*player {
+	connected := make([]*player, 0, len(s.players))
+	for _, p := range s.players {
+		if p.connected() {
+			connected = append(connected, p)
+		}
+	}
+
+	return connected
+}
+
+// scores returns every player's score, highest first.
+func (s *quizServer) scores() []playerScore {
+	scores := make([]playerScore, 0, len(s.players))
+	for _, p := range s.players {
+		scores = append(scores, playerScore{name: p.name, score: p.score})
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+
+	return scores
+}
+
+// broadcast sends msg to every connected player.
+func (s *quizServer) broadcast(msg string) {
+	for _, p := range s.connectedPlayers() {
+		s.write(p, msg)
+	}
+}
+
+// write sends msg to a single player. Errors are only logged, as a player leaving shouldn't stop the quiz.
+func (s *quizServer) write(p *player, msg string) {
+	if _, err := fmt.Fprint(p.conn, msg); err != nil {
+		log.Printf("problem writing to %s: %v", p.conn.RemoteAddr(), err)
+	}
+}
+
+// disconnectAll closes every player's connection once the quiz has finished.
+func (s *quizServer) disconnectAll() {
+	for _, p := range s.players {
+		_ = p.conn.Close()
+	}
+}
+
+// formatScoreboard returns the scores as a ranked list.
+func formatScoreboard(scores []playerScore) string {
+	var b strings.Builder
+	for i, ps := range scores {
+		fmt.Fprintf(&b, "  %d. %s %d\n", i+1, ps.name, ps.score)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClient connects to the quiz server as a player, sends all of its input up front and returns everything the
+// server sent back once the server disconnects it.
+func testClient(t *testing.T, addr, input string) <-chan string {
+	output := make(chan string, 1)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("connecting to the quiz server: %v", err)
+	}
+
+	go func() {
+		defer conn.Close()
+
+		_, _ = io.WriteString(conn, input)
+		b, _ := io.ReadAll(conn)
+		output <- string(b)
+	}()
+
+	return output
+}
+
+func Test_quizServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	questions := []quizQuestion{{question: "1+1", answer: "2"}, {question: "2+2", answer: "4"}}
+	server := newQuizServer(listener, questions, 2, 5*time.Second)
+
+	type result struct {
+		scores []playerScore
+		err    error
+	}
+	done := make(chan result)
+	go func() {
+		scores, err := server.run()
+		done <- result{scores: scores, err: err}
+	}()
+
+	alice := testClient(t, listener.Addr().String(), "alice\n2\n4\n")
+	bob := testClient(t, listener.Addr().String(), "bob\n2\nfive\n")
+
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the quiz to finish")
+	}
+	assert.NoError(t, r.err)
+	assert.Equal(t, []playerScore{{name: "alice", score: 2}, {name: "bob", score: 1}}, r.scores)
+
+	aliceOutput, bobOutput := <-alice, <-bob
+	for _, output := range []string{aliceOutput, bobOutput} {
+		assert.Contains(t, output, "Question #1: 1+1 = ", "expected every player to be asked the same question")
+		assert.Contains(t, output, "Scoreboard after round 1 of 2:\n  1. alice 1\n  2. bob 1\n")
+		assert.Contains(t, output, "Final scores:\n  1. alice 2\n  2. bob 1\n")
+	}
+	assert.Contains(t, bobOutput, "Wrong, the answer was 4")
+	assert.Equal(t, 1, strings.Count(aliceOutput, "Welcome to the quiz!"))
+}
+
+func Test_quizServerRoundTimesOut(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	server := newQuizServer(listener, []quizQuestion{{question: "1+1", answer: "2"}}, 1, 50*time.Millisecond)
+	done := make(chan []playerScore)
+	go func() {
+		scores, _ := server.run()
+		done <- scores
+	}()
+
+	// The player joins but never answers, so the round has to finish on the time limit
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, _ = io.WriteString(conn, "carol\n")
+
+	select {
+	case scores := <-done:
+		assert.Equal(t, []playerScore{{name: "carol", score: 0}}, scores)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the round to finish")
+	}
+}
+
+func Test_quizServerIdleConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	server := newQuizServer(listener, []quizQuestion{{question: "1+1", answer: "2"}}, 1, 5*time.Second)
+	done := make(chan []playerScore)
+	go func() {
+		scores, _ := server.run()
+		done <- scores
+	}()
+
+	// The first connection never sends a name, which mustn't stop the next player from joining
+	idle, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	defer idle.Close()
+	dave := testClient(t, listener.Addr().String(), "dave\n2\n")
+
+	select {
+	case scores := <-done:
+		assert.Equal(t, []playerScore{{name: "dave", score: 1}}, scores)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the quiz to finish")
+	}
+	assert.Contains(t, <-dave, "Final scores:\n  1. dave 1\n")
+}
+
+func Test_quizServerDiscardsLateAnswers(t *testing.T) {
+	conn, client := net.Pipe()
+	defer client.Close()
+	go func() { _, _ = io.Copy(io.Discard, client) }()
+
+	p := &player{name: "erin", conn: conn, answers: make(chan string, 1), left: make(chan struct{})}
+	server := &quizServer{players: []*player{p}, questionLimit: 50 * time.Millisecond}
+
+	done := make(chan struct{})
+	go func() {
+		server.playRound(1, quizQuestion{question: "1+1", answer: "2"})
+		close(done)
+	}()
+
+	// The player's answer arrives just after the round timed out, so it mustn't be left for the next round
+	time.Sleep(200 * time.Millisecond)
+	p.answers <- "2"
+	<-done
+
+	assert.Equal(t, 0, p.score)
+	assert.Empty(t, p.answers, "expected the late answer to be discarded rather than used for the next question")
+}
+
+func Test_runCommandServeLimit(t *testing.T) {
+	opts := commandOptions{quizPath: "problems.csv", limitSet: true, historyPath: filepath.Join(t.TempDir(), "history.jsonl")}
+
+	err := runCommand([]string{"serve"}, opts)
+	assert.ErrorContains(t, err, "-limit", "expected -limit to be rejected rather than ignored")
+}
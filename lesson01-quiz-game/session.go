@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// savedSession is the state of a paused quiz, written to the session file so that it can be continued with -resume.
+type savedSession struct {
+	QuizFile        string    `json:"quiz_file"`
+	QuizHash        string    `json:"quiz_hash"`
+	Format          string    `json:"format,omitempty"`
+	Match           string    `json:"match,omitempty"`
+	Player          string    `json:"player"`
+	Practice        bool      `json:"practice,omitempty"`
+	Adaptive        bool      `json:"adaptive,omitempty"`
+	Generate        string    `json:"generate,omitempty"`
+	Seed            int64     `json:"seed,omitempty"`
+	Pin             string    `json:"sha256,omitempty"`
+	QuestionLimitMs int64     `json:"question_limit_ms"`
+	RemainingMs     int64     `json:"remaining_ms"`
+	ElapsedMs       int64     `json:"elapsed_ms"`
+	PausedAt        time.Time `json:"paused_at"`
+	// Order is the index of each question in the quiz, in the order they are being asked, with Questions being
+	// their text
+	Order     []int         `json:"order"`
+	Questions []string      `json:"questions"`
+	Answers   []savedAnswer `json:"answers"`
+}
+
+// savedAnswer is an answer which was given before the quiz was paused.
+type savedAnswer struct {
+	Number     int    `json:"number"`
+	Given      string `json:"given"`
+	Result     string `json:"result"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// questionOrder returns the text of each question, in the order they are being asked.
+func questionOrder(questions []quizQuestion) []string {
+	order := make([]string, 0, len(questions))
+	for _, q := range questions {
+		order = append(order, q.question)
+	}
+
+	return order
+}
+
+// questionIndexes returns the index of each question in the loaded quiz, in the order they are being asked.
+func questionIndexes(questions []quizQuestion) []int {
+	order := make([]int, 0, len(questions))
+	for _, q := range questions {
+		order = append(order, q.index)
+	}
+
+	return order
+}
+
+// savedAnswers converts the answers received so far so that they can be saved.
+func savedAnswers(records []answerRecord) []savedAnswer {
+	answers := make([]savedAnswer, 0, len(records))
+	for _, r := range records {
+		answers = append(answers, savedAnswer{Number: r.number, Given: r.given, Result: r.outcome.String(), DurationMs: r.duration.Milliseconds()})
+	}
+
+	return answers
+}
+
+// saveSession writes the paused quiz to path.
+func saveSession(path string, s savedSession) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding session: %v", err)
+	}
+
+	if err = os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("writing session file '%s': %v", path, err)
+	}
+
+	return nil
+}
+
+// loadSession reads a paused quiz from path.
+func loadSession(path string) (savedSession, error) {
+	var s savedSession
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("reading session file '%s': %v", path, err)
+	}
+	if err = json.Unmarshal(b, &s); err != nil {
+		return s, fmt.Errorf("decoding session file '%s': %v", path, err)
+	}
+	if len(s.Answers) > len(s.Questions) {
+		return s, fmt.Errorf("session file '%s' has more answers than questions", path)
+	}
+	if len(s.Order) != len(s.Questions) {
+		return s, fmt.Errorf("session file '%s' doesn't record the order of its questions", path)
+	}
+
+	return s, nil
+}
+
+// resumePin returns the SHA-256 pin to check the quiz file against when resuming, which is the one the quiz was
+// started with. A different pin given when resuming is refused, rather than silently replacing the original one.
+func (s savedSession) resumePin(pin string) (string, error) {
+	if pin != "" && s.Pin != "" && !strings.EqualFold(strings.TrimSpace(pin), strings.TrimSpace(s.Pin)) {
+		return "", fmt.Errorf("the quiz was paused with -sha256 %s, which doesn't match %s", s.Pin, pin)
+	}
+	if s.Pin != "" {
+		return s.Pin, nil
+	}
+
+	return pin, nil
+}
+
+// restore puts the questions back into the order they were being asked in, and rebuilds the answers given before
+// the quiz was paused. The quiz file must not have changed since the session was saved.
+func (s savedSession) restore(quizHash string, loaded []quizQuestion) ([]quizQuestion, []answerRecord, error) {
+	if quizHash != s.QuizHash {
+		return nil, nil, fmt.Errorf("the quiz file '%s' has changed since the session was paused", s.QuizFile)
+	}
+
+	// The questions are found by their index, as several questions can have the same text
+	questions := make([]quizQuestion, 0, len(s.Order))
+	for i, index := range s.Order {
+		if index < 0 || index >= len(loaded) || loaded[index].question != s.Questions[i] {
+			return nil, nil, fmt.Errorf("question '%s' from the session is no longer in the quiz file", s.Questions[i])
+		}
+		questions = append(questions, loaded[index])
+	}
+
+	records := make([]answerRecord, 0, len(s.Answers))
+	for _, a := range s.Answers {
+		if a.Number < 0 || a.Number >= len(questions) {
+			return nil, nil, fmt.Errorf("answer for unknown question #%d in the session", a.Number+1)
+		}
+		o, err := parseOutcome(a.Result)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, answerRecord{
+			number:   a.Number,
+			question: questions[a.Number],
+			given:    a.Given,
+			outcome:  o,
+			duration: time.Duration(a.DurationMs) * time.Millisecond,
+		})
+	}
+
+	return questions, records, nil
+}
+
+// parseOutcome is the reverse of outcome.String.
+func parseOutcome(s string) (outcome, error) {
+	for _, o := range []outcome{answeredCorrectly, answeredIncorrectly, timedOut} {
+		if o.String() == s {
+			return o, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown result '%s' in the session", s)
+}
+
+// removeSession deletes the session file once a resumed quiz has finished. path is empty when the quiz wasn't resumed.
+func removeSession(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		log.Printf("Problem removing the session file: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sessionRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quiz-session.json")
+	questions := []quizQuestion{{question: "3+3", answer: "6", index: 2}, {question: "1+1", answer: "2", index: 0}, {question: "2+2", answer: "4", index: 1}}
+	records := []answerRecord{
+		{number: 0, question: questions[0], given: "6", outcome: answeredCorrectly, duration: 1500 * time.Millisecond},
+		{number: 1, question: questions[1], outcome: timedOut, duration: 5 * time.Second},
+	}
+
+	err := saveSession(path, savedSession{
+		QuizFile:    "problems.csv",
+		QuizHash:    "hash",
+		Player:      "alice",
+		RemainingMs: 12000,
+		ElapsedMs:   18000,
+		Order:       questionIndexes(questions),
+		Questions:   questionOrder(questions),
+		Answers:     savedAnswers(records),
+	})
+	assert.NoError(t, err)
+
+	session, err := loadSession(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", session.Player)
+	assert.Equal(t, int64(12000), session.RemainingMs)
+
+	// The quiz file is loaded in its original order, which differs from the order the questions were asked in
+	loaded := []quizQuestion{questions[1], questions[2], questions[0]}
+	restored, restoredRecords, err := session.restore("hash", loaded)
+	assert.NoError(t, err)
+	assert.Equal(t, questions, restored, "expected the questions to be asked in the same order as before the pause")
+	assert.Equal(t, records, restoredRecords, "expected the answers given before the pause to be kept")
+
+	_, _, err = session.restore("changed", loaded)
+	assert.Error(t, err, "expected an error when the quiz file has changed since the pause")
+
+	_, _, err = session.restore("hash", loaded[:2])
+	assert.Error(t, err, "expected an error when a question is missing from the quiz file")
+}
+
+func Test_sessionDuplicateQuestions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quiz-session.json")
+	loaded := []quizQuestion{{question: "capital?", answer: "Paris", index: 0}, {question: "capital?", answer: "Rome", index: 1}}
+	questions := []quizQuestion{loaded[1], loaded[0]}
+
+	err := saveSession(path, savedSession{QuizHash: "hash", Order: questionIndexes(questions), Questions: questionOrder(questions)})
+	assert.NoError(t, err)
+
+	session, err := loadSession(path)
+	assert.NoError(t, err)
+
+	restored, _, err := session.restore("hash", loaded)
+	assert.NoError(t, err)
+	assert.Equal(t, questions, restored, "expected questions with the same text to be restored separately")
+}
+
+func Test_sessionResumePin(t *testing.T) {
+	pinned := savedSession{Pin: "abc123"}
+
+	pin, err := pinned.resumePin("")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", pin, "expected the pin the quiz was started with to be checked when resuming")
+
+	pin, err = pinned.resumePin("ABC123")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", pin)
+
+	_, err = pinned.resumePin("def456")
+	assert.Error(t, err, "expected an error when resuming with a different pin")
+
+	pin, err = savedSession{}.resumePin("def456")
+	assert.NoError(t, err)
+	assert.Equal(t, "def456", pin, "expected a pin to be checked when resuming a quiz which wasn't pinned")
+}
+
+func Test_loadSessionInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quiz-session.json")
+	err := saveSession(path, savedSession{Order: []int{0}, Questions: []string{"1+1"}, Answers: []savedAnswer{{Number: 0}, {Number: 1}}})
+	assert.NoError(t, err)
+
+	_, err = loadSession(path)
+	assert.Error(t, err, "expected an error when there are more answers than questions")
+
+	err = saveSession(path, savedSession{Questions: []string{"1+1"}})
+	assert.NoError(t, err)
+
+	_, err = loadSession(path)
+	assert.Error(t, err, "expected an error when the order of the questions isn't recorded")
+
+	_, err = loadSession(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err, "expected an error when the session file doesn't exist")
+}
+
+func Test_parseOutcome(t *testing.T) {
+	for _, o := range []outcome{answeredCorrectly, answeredIncorrectly, timedOut} {
+		parsed, err := parseOutcome(o.String())
+		assert.NoError(t, err)
+		assert.Equal(t, o, parsed)
+	}
+
+	_, err := parseOutcome("unanswered")
+	assert.Error(t, err)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// pauseSignals are the signals which pause the quiz. Only Ctrl-C is available on this platform.
+var pauseSignals = []os.Signal{os.Interrupt}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// pauseSignals are the signals which pause the quiz. Ctrl-Z (SIGTSTP) pauses rather than suspending the process,
+// so the time spent suspended isn't taken off the quiz timer.
+var pauseSignals = []os.Signal{os.Interrupt, syscall.SIGTSTP}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// QuestionSource parses quiz questions from a quiz file in a particular format.
+// Rows which cannot be turned into a question are returned as skipped rows rather than failing the whole load.
+type QuestionSource interface {
+	Load(r io.Reader) ([]quizQuestion, []skippedRow, error)
+}
+
+// skippedRow records a row in a quiz file which was not loaded, along with the reason why.
+type skippedRow struct {
+	line   int
+	reason string
+}
+
+func (s skippedRow) String() string {
+	return fmt.Sprintf("line %d: %s", s.line, s.reason)
+}
+
+// questionSources maps each supported -format value to its loader.
+var questionSources = map[string]QuestionSource{
+	"csv":      csvSource{},
+	"json":     jsonSource{},
+	"yaml":     yamlSource{},
+	"markdown": markdownSource{},
+}
+
+// formatExtensions maps quiz file extensions to the format used to load them when -format is not set.
+var formatExtensions = map[string]string{
+	".csv":      "csv",
+	".json":     "json",
+	".yaml":     "yaml",
+	".yml":      "yaml",
+	".md":       "markdown",
+	".markdown": "markdown",
+}
+
+// sourceFor returns the QuestionSource to use for path. An explicit format takes precedence over the file extension.
+func sourceFor(path, format string) (QuestionSource, error) {
+	if format == "" {
+		ext := strings.ToLower(filepath.Ext(path))
+		f, ok := formatExtensions[ext]
+		if !ok {
+			return nil, fmt.Errorf("unable to detect the format of '%s' from its extension, set -format to one of: %s", path, supportedFormats())
+		}
+		format = f
+	}
+
+	source, ok := questionSources[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported quiz format '%s', expected one of: %s", format, supportedFormats())
+	}
+
+	return source, nil
+}
+
+// supportedFormats returns the -format values as a sorted comma separated list for use in error messages.
+func supportedFormats() string {
+	formats := make([]string, 0, len(questionSources))
+	for f := range questionSources {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+
+	return strings.Join(formats, ", ")
+}
+
+// maxChoices is the most choices a question can have, as each is labelled with a single letter from a to z.
+const maxChoices = 26
+
+// listSeparator separates the entries of list columns (such as choices) in the tabular CSV and Markdown formats.
+const listSeparator = "|"
+
+// questionRecord is the on-disk shape of a question, shared by the structured (JSON/YAML) and tabular loaders.
+type questionRecord struct {
+	Question     string   `json:"question" yaml:"question"`
+	Answer       string   `json:"answer" yaml:"answer"`
+	Choices      []string `json:"choices,omitempty" yaml:"choices,omitempty"`
+	Alternatives []string `json:"alternatives,omitempty" yaml:"alternatives,omitempty"`
+	Tolerance    *float64 `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+	Limit        string   `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Match        string   `json:"match,omitempty" yaml:"match,omitempty"`
+	Difficulty   string   `json:"difficulty,omitempty" yaml:"difficulty,omitempty"`
+	Tags         []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// toQuestion validates a questionRecord and converts it into a quizQuestion.
+func (r questionRecord) toQuestion() (quizQuestion, error) {
+	if strings.TrimSpace(r.Question) == "" {
+		return quizQuestion{}, errors.New("empty question")
+	}
+	if strings.TrimSpace(r.Answer) == "" {
+		return quizQuestion{}, errors.New("empty answer")
+	}
+
+	q := quizQuestion{question: r.Question, answer: r.Answer, choices: r.Choices, alternatives: r.Alternatives}
+
+	if len(q.choices) > maxChoices {
+		return quizQuestion{}, fmt.Errorf("%d choices is too many, a question can have at most %d", len(q.choices), maxChoices)
+	}
+	if len(q.choices) > 0 {
+		// Store the answer as the text of the choice, so a letter or the choice itself can be given in the quiz file
+		choice, ok := q.choiceFor(q.answer)
+		if !ok {
+			return quizQuestion{}, fmt.Errorf("answer '%s' is not one of the choices", r.Answer)
+		}
+		q.answer = choice
+	}
+
+	matcher, err := parseMatcher(r.Match)
+	if err != nil {
+		return quizQuestion{}, err
+	}
+	if r.Tolerance != nil {
+		if *r.Tolerance < 0 || math.IsNaN(*r.Tolerance) || math.IsInf(*r.Tolerance, 0) {
+			return quizQuestion{}, errors.New("tolerance must be a finite number which isn't negative")
+		}
+		// A tolerance implies comparing the answers as numbers
+		if _, ok := matcher.(numericMatcher); !ok && matcher != nil {
+			return quizQuestion{}, fmt.Errorf("tolerance can only be used with the numeric matcher, not '%s'", r.Match)
+		}
+		matcher = numericMatcher{tolerance: *r.Tolerance}
+	}
+	if matcher != nil {
+		if err = validateAnswers(q, matcher); err != nil {
+			return quizQuestion{}, err
+		}
+		q.matcher = matcher
+	}
+
+	if strings.TrimSpace(r.Limit) != "" {
+		limit, err := time.ParseDuration(strings.TrimSpace(r.Limit))
+		if err != nil || limit <= 0 {
+			return quizQuestion{}, fmt.Errorf("invalid limit '%s', expected a positive duration such as 10s", r.Limit)
+		}
+		q.limit = limit
+	}
+
+	if q.difficulty, err = parseDifficulty(r.Difficulty); err != nil {
+		return quizQuestion{}, err
+	}
+	for _, tag := range r.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			q.tags = append(q.tags, tag)
+		}
+	}
+
+	return q, nil
+}
+
+// validateAnswers checks that every accepted answer of a question can be used with matcher.
+func validateAnswers(q quizQuestion, matcher answerMatcher) error {
+	for _, a := range q.acceptedAnswers() {
+		if err := matcher.validate(a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordFromColumns builds a questionRecord from a row of cells using the column names from a header row.
+// Column names are matched case-insensitively and unknown columns are ignored.
+// List columns hold several values separated by listSeparator e.g. 'Paris|London|Rome'.
+func recordFromColumns(header, cells []string) (questionRecord, error) {
+	if len(cells) != len(header) {
+		return questionRecord{}, fmt.Errorf("expected %d fields but found %d", len(header), len(cells))
+	}
+
+	var r questionRecord
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "question":
+			r.Question = cells[i]
+		case "answer":
+			r.Answer = cells[i]
+		case "choices":
+			r.Choices = splitList(cells[i])
+		case "alternatives":
+			r.Alternatives = splitList(cells[i])
+		case "tolerance":
+			if strings.TrimSpace(cells[i]) == "" {
+				continue
+			}
+			t, err := strconv.ParseFloat(strings.TrimSpace(cells[i]), 64)
+			if err != nil {
+				return questionRecord{}, fmt.Errorf("invalid tolerance '%s'", cells[i])
+			}
+			r.Tolerance = &t
+		case "limit":
+			r.Limit = cells[i]
+		case "match":
+			r.Match = cells[i]
+		case "difficulty":
+			r.Difficulty = cells[i]
+		case "tags":
+			r.Tags = splitList(cells[i])
+		}
+	}
+
+	return r, nil
+}
+
+// splitList splits a list column into its trimmed, non-empty values.
+func splitList(cell string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(cell, listSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values
+}
+
+// validateHeader checks that a header row contains the columns every question needs.
+func validateHeader(header []string) error {
+	found := make(map[string]bool)
+	for _, column := range header {
+		found[strings.ToLower(strings.TrimSpace(column))] = true
+	}
+
+	for _, required := range []string{"question", "answer"} {
+		if !found[required] {
+			return fmt.Errorf("header is missing the '%s' column", required)
+		}
+	}
+
+	return nil
+}
+
+// csvSource loads questions from a CSV file in the format of 'question,answer'.
+// If the first row is a header naming the columns (which must include 'question' and 'answer') then the optional
+// 'choices', 'alternatives', 'tolerance', 'limit', 'match', 'difficulty' and 'tags' columns can also be used.
+type csvSource struct{}
+
+func (csvSource) Load(r io.Reader) ([]quizQuestion, []skippedRow, error) {
+	questions := make([]quizQuestion, 0)
+	skipped := make([]skippedRow, 0)
+
+	reader := csv.NewReader(r)
+	// Check the number of fields ourselves so that irregular rows can be reported rather than failing the reader
+	reader.FieldsPerRecord = -1
+
+	var header []string
+	firstRow := true
+	for {
+		csvRecord, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				skipped = append(skipped, skippedRow{line: parseErr.StartLine, reason: parseErr.Err.Error()})
+				firstRow = false
+				continue
+			}
+			return nil, nil, fmt.Errorf("reading CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if firstRow {
+			firstRow = false
+			if validateHeader(csvRecord) == nil {
+				header = csvRecord
+				continue
+			}
+		}
+
+		var record questionRecord
+		if header != nil {
+			record, err = recordFromColumns(header, csvRecord)
+			if err != nil {
+				skipped = append(skipped, skippedRow{line: line, reason: err.Error()})
+				continue
+			}
+		} else {
+			// Without a header expect exactly one question and answer in the CSV record
+			if len(csvRecord) != 2 {
+				skipped = append(skipped, skippedRow{line: line, reason: fmt.Sprintf("expected 2 fields but found %d", len(csvRecord))})
+				continue
+			}
+			record = questionRecord{Question: csvRecord[0], Answer: csvRecord[1]}
+		}
+
+		q, err := record.toQuestion()
+		if err != nil {
+			skipped = append(skipped, skippedRow{line: line, reason: err.Error()})
+			continue
+		}
+		q.line = line
+		questions = append(questions, q)
+	}
+
+	return questions, skipped, nil
+}
+
+// jsonSource loads questions from a JSON array of objects with 'question' and 'answer' keys, plus the optional
+// 'choices', 'alternatives', 'tolerance', 'limit', 'match', 'difficulty' and 'tags' keys.
+type jsonSource struct{}
+
+func (jsonSource) Load(r io.Reader) ([]quizQuestion, []skippedRow, error) {
+	questions := make([]quizQuestion, 0)
+	skipped := make([]skippedRow, 0)
+
+	// Keep a copy of the input so that decoder offsets can be converted into line numbers
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading JSON: %v", err)
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return questions, skipped, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding JSON: %v", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, nil, errors.New("decoding JSON: expected an array of questions")
+	}
+
+	for decoder.More() {
+		// InputOffset points at the end of the previous element, so skip past the separator to find this one
+		offset := int(decoder.InputOffset())
+		for offset < len(b) && strings.ContainsRune(", \t\r\n", rune(b[offset])) {
+			offset++
+		}
+		line := 1 + bytes.Count(b[:offset], []byte("\n"))
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			// Syntax errors leave the decoder in an unknown state, so nothing after this point can be trusted
+			return nil, nil, fmt.Errorf("decoding JSON at line %d: %v", line, err)
+		}
+
+		var record questionRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			skipped = append(skipped, skippedRow{line: line, reason: err.Error()})
+			continue
+		}
+
+		q, err := record.toQuestion()
+		if err != nil {
+			skipped = append(skipped, skippedRow{line: line, reason: err.Error()})
+			continue
+		}
+		q.line = line
+		questions = append(questions, q)
+	}
+
+	return questions, skipped, nil
+}
+
+// yamlSource loads questions from a YAML sequence of mappings with the same keys as jsonSource.
+type yamlSource struct{}
+
+func (yamlSource) Load(r io.Reader) ([]quizQuestion, []skippedRow, error) {
+	questions := make([]quizQuestion, 0)
+	skipped := make([]skippedRow, 0)
+
+	// Decode into a node tree rather than a struct so that each entry keeps its line number
+	var doc yaml.Node
+	err := yaml.NewDecoder(r).Decode(&doc)
+	if err == io.EOF {
+		return questions, skipped, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding YAML: %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return questions, skipped, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.SequenceNode {
+		return nil, nil, fmt.Errorf("decoding YAML: expected a list of questions at line %d", root.Line)
+	}
+
+	for _, item := range root.Content {
+		var record questionRecord
+		if err := item.Decode(&record); err != nil {
+			skipped = append(skipped, skippedRow{line: item.Line, reason: err.Error()})
+			continue
+		}
+
+		q, err := record.toQuestion()
+		if err != nil {
+			skipped = append(skipped, skippedRow{line: item.Line, reason: err.Error()})
+			continue
+		}
+		q.line = item.Line
+		questions = append(questions, q)
+	}
+
+	return questions, skipped, nil
+}
+
+// markdownSource loads questions from the first Markdown table in a file. The table must have a header row
+// with 'question' and 'answer' columns (plus any of the optional CSV columns), followed by the usual |---|---|
+// delimiter row. List values are separated with an escaped pipe e.g. 'Paris \| London'.
+type markdownSource struct{}
+
+func (markdownSource) Load(r io.Reader) ([]quizQuestion, []skippedRow, error) {
+	questions := make([]quizQuestion, 0)
+	skipped := make([]skippedRow, 0)
+
+	var header []string
+	delimiterSeen := false
+	line := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if !strings.HasPrefix(text, "|") {
+			// Any text before the table is ignored, and the table finishes at the first line which isn't a row
+			if header != nil {
+				break
+			}
+			continue
+		}
+		cells := splitMarkdownRow(text)
+
+		if header == nil {
+			if err := validateHeader(cells); err != nil {
+				return nil, nil, fmt.Errorf("reading Markdown table at line %d: %v", line, err)
+			}
+			header = cells
+			continue
+		}
+
+		if !delimiterSeen {
+			if !isMarkdownDelimiterRow(cells) {
+				return nil, nil, fmt.Errorf("reading Markdown table at line %d: expected a delimiter row such as |---|---|", line)
+			}
+			delimiterSeen = true
+			continue
+		}
+
+		record, err := recordFromColumns(header, cells)
+		if err != nil {
+			skipped = append(skipped, skippedRow{line: line, reason: err.Error()})
+			continue
+		}
+
+		q, err := record.toQuestion()
+		if err != nil {
+			skipped = append(skipped, skippedRow{line: line, reason: err.Error()})
+			continue
+		}
+		q.line = line
+		questions = append(questions, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading Markdown: %v", err)
+	}
+
+	return questions, skipped, nil
+}
+
+// splitMarkdownRow splits a Markdown table row into its trimmed cells. Escaped pipes (\|) are kept as part of the cell.
+func splitMarkdownRow(row string) []string {
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = strings.TrimSuffix(row, "|")
+	}
+
+	cells := make([]string, 0)
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		if row[i] == '\\' && i+1 < len(row) && row[i+1] == '|' {
+			cell.WriteByte('|')
+			i++
+			continue
+		}
+		if row[i] == '|' {
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(row[i])
+	}
+	cells = append(cells, strings.TrimSpace(cell.String()))
+
+	return cells
+}
+
+// isMarkdownDelimiterRow reports whether every cell is a delimiter such as '---', ':--' or ':-:'.
+func isMarkdownDelimiterRow(cells []string) bool {
+	for _, c := range cells {
+		if strings.Trim(c, ":-") != "" || !strings.Contains(c, "-") {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_questionSources(t *testing.T) {
+	tt := []struct {
+		name              string
+		path              string
+		expectedQuestions []string
+		expectedSkipped   []skippedRow
+	}{
+		{name: "json skips empty and mistyped answers", path: "./testdata/bad-record.json", expectedQuestions: []string{"1+2", "4+4"},
+			expectedSkipped: []skippedRow{{line: 3, reason: "empty answer"}, {line: 4, reason: "json: cannot unmarshal number into Go struct field questionRecord.answer of type string"}}},
+		{name: "yaml skips empty questions and bad types", path: "./testdata/bad-record.yaml", expectedQuestions: []string{"1+2"},
+			expectedSkipped: []skippedRow{{line: 3, reason: "empty question"}, {line: 5}}},
+		{name: "markdown skips irregular rows and keeps escaped pipes", path: "./testdata/bad-record.md", expectedQuestions: []string{"1+2", "a | b"},
+			expectedSkipped: []skippedRow{{line: 4, reason: "expected 2 fields but found 3"}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			source, err := sourceFor(tc.path, "")
+			assert.NoError(t, err)
+
+			f, err := os.Open(tc.path)
+			assert.NoError(t, err)
+			defer f.Close()
+
+			questions, skipped, err := source.Load(f)
+			assert.NoError(t, err)
+
+			names := make([]string, 0)
+			for _, q := range questions {
+				names = append(names, q.question)
+			}
+			assert.Equal(t, tc.expectedQuestions, names)
+
+			assert.Equal(t, len(tc.expectedSkipped), len(skipped), "unexpected number of skipped rows: %v", skipped)
+			for i := range tc.expectedSkipped {
+				if i >= len(skipped) {
+					break
+				}
+				assert.Equal(t, tc.expectedSkipped[i].line, skipped[i].line)
+				// Some reasons come straight from third party decoders, so only check the ones we control
+				if tc.expectedSkipped[i].reason != "" {
+					assert.Equal(t, tc.expectedSkipped[i].reason, skipped[i].reason)
+				}
+			}
+		})
+	}
+}
+
+func Test_markdownSourceRequiresHeader(t *testing.T) {
+	_, _, err := markdownSource{}.Load(strings.NewReader("| foo | bar |\n|---|---|\n| 1 | 2 |\n"))
+	assert.Error(t, err, "expected an error when the table has no question/answer columns")
+}
+
+func Test_sourceFor(t *testing.T) {
+	_, err := sourceFor("quiz.yml", "")
+	assert.NoError(t, err)
+
+	_, err = sourceFor("quiz.txt", "markdown")
+	assert.NoError(t, err, "expected -format to be used when the extension is unknown")
+
+	_, err = sourceFor("quiz.csv", "xml")
+	assert.Error(t, err, "expected an error for an unsupported format")
+}
+
+func Test_csvSourceWithHeader(t *testing.T) {
+	f, err := os.Open("./testdata/extended.csv")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	questions, skipped, err := csvSource{}.Load(f)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(questions))
+
+	assert.Equal(t, "Paris", questions[0].answer, "expected the answer letter to be resolved to the text of the choice")
+	assert.Equal(t, []string{"London", "Paris", "Rome"}, questions[0].choices)
+	assert.Equal(t, []string{"Pacific Ocean", "The Pacific"}, questions[1].alternatives)
+	assert.Equal(t, numericMatcher{tolerance: 0.005}, questions[2].matcher)
+
+	assert.Equal(t, []skippedRow{
+		{line: 5, reason: "answer 'Madrid' is not one of the choices"},
+		{line: 6, reason: "answer 'about 1.41' must be a number for the numeric matcher"},
+	}, skipped)
+}
+
+func Test_tooManyChoices(t *testing.T) {
+	choices := make([]string, maxChoices+1)
+	for i := range choices {
+		choices[i] = fmt.Sprintf("choice %d", i+1)
+	}
+
+	_, err := questionRecord{Question: "which?", Answer: "choice 1", Choices: choices}.toQuestion()
+	assert.EqualError(t, err, "27 choices is too many, a question can have at most 26")
+
+	_, err = questionRecord{Question: "which?", Answer: "z", Choices: choices[:maxChoices]}.toQuestion()
+	assert.NoError(t, err, "expected every letter from a to z to be usable")
+}
+
+func Test_questionLimits(t *testing.T) {
+	f, err := os.Open("./testdata/limits.yaml")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	questions, skipped, err := yamlSource{}.Load(f)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, len(questions))
+	assert.Equal(t, 5*time.Second, questions[0].limit)
+	assert.Equal(t, time.Duration(0), questions[1].limit, "expected no limit when the question doesn't set one")
+	assert.Equal(t, []skippedRow{{line: 6, reason: "invalid limit 'soon', expected a positive duration such as 10s"}}, skipped)
+}
+
+func Test_questionMatchers(t *testing.T) {
+	f, err := os.Open("./testdata/matchers.yaml")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	questions, skipped, err := yamlSource{}.Load(f)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, len(questions))
+	assert.True(t, questions[0].IsCorrect("bern "))
+	assert.True(t, questions[1].IsCorrect("eight"))
+	assert.True(t, questions[2].IsCorrect("Yellow"))
+	assert.Equal(t, []skippedRow{{line: 10, reason: "tolerance can only be used with the numeric matcher, not 'regex'"}}, skipped)
+}
+
+func Test_questionToleranceNotFinite(t *testing.T) {
+	questions, skipped, err := csvSource{}.Load(strings.NewReader("question,answer,tolerance\npi,3.14,0.01\ne,2.72,nan\nphi,1.62,inf\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(questions))
+	assert.Equal(t, []skippedRow{
+		{line: 3, reason: "tolerance must be a finite number which isn't negative"},
+		{line: 4, reason: "tolerance must be a finite number which isn't negative"},
+	}, skipped)
+}
+
+func Test_questionTags(t *testing.T) {
+	f, err := os.Open("./testdata/tagged.csv")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	questions, skipped, err := csvSource{}.Load(f)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5, len(questions))
+	assert.Equal(t, easy, questions[0].difficulty)
+	assert.Equal(t, []string{"geography", "capitals"}, questions[1].tags)
+	assert.Equal(t, unrated, questions[4].difficulty, "expected no difficulty when the question doesn't set one")
+	assert.Equal(t, []skippedRow{{line: 7, reason: "unknown difficulty 'trivial', expected one of: easy, medium, hard"}}, skipped)
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// sessionRetention is how long a finished session is kept so that its result can still be fetched.
+const sessionRetention = time.Hour
+
+var (
+	errSessionNotFound = errors.New("session not found")
+	errQuizFinished    = errors.New("the quiz has finished")
+	errTimeLimit       = errors.New("the time limit for the quiz has expired")
+)
+
+// quizSession is one run through the quiz by a browser or API client. The time limit is enforced using the
+// deadline set when the session starts, so a client can't extend the quiz by not asking for the next question.
+type quizSession struct {
+	id        string
+	questions []quizQuestion
+	records   []answerRecord
+	startedAt time.Time
+	deadline  time.Time
+	// askedAt is when the current question was first fetched, used to work out how long it took to answer
+	askedAt time.Time
+}
+
+// finished reports whether every question has been answered or the time limit has expired.
+func (s *quizSession) finished(now time.Time) bool {
+	return len(s.records) >= len(s.questions) || !now.Before(s.deadline)
+}
+
+// webServer serves the quiz as a REST API, along with a server rendered HTML front end which uses the same sessions.
+type webServer struct {
+	quizFile  string
+	questions []quizQuestion
+	limit     time.Duration
+	random    bool
+	templates *template.Template
+	now       func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*quizSession
+}
+
+// newWebServer returns a webServer which gives each session limit to answer all the questions.
+func newWebServer(quizFile string, questions []quizQuestion, limit time.Duration, random bool) (*webServer, error) {
+	tmpl, err := template.New("").Funcs(template.FuncMap{"letter": func(i int) string { return string(choiceLetter(i)) }}).ParseFS(templateFiles, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTML templates: %v", err)
+	}
+
+	return &webServer{
+		quizFile:  quizFile,
+		questions: questions,
+		limit:     limit,
+		random:    random,
+		templates: tmpl,
+		now:       time.Now,
+		sessions:  make(map[string]*quizSession),
+	}, nil
+}
+
+// routes returns the handler for both the API (under /api/) and the HTML front end.
+//
+//	POST /api/sessions                 start a session
+//	GET  /api/sessions/{id}/question   fetch the next question
+//	POST /api/sessions/{id}/answer     submit an answer to the current question
+//	GET  /api/sessions/{id}/result     get the result, which is final once the quiz has finished
+func (s *webServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", s.handleAPISessions)
+	mux.HandleFunc("/api/sessions/", s.handleAPISession)
+	mux.HandleFunc("/play", s.handlePlayStart)
+	mux.HandleFunc("/play/", s.handlePlay)
+	mux.HandleFunc("/", s.handleIndex)
+
+	return mux
+}
+
+// startSession creates a new session, clearing out any old sessions at the same time.
+func (s *webServer) startSession() (*quizSession, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generating session ID: %v", err)
+	}
+
+	questions := s.questions
+	if s.random {
+		questions = randomiseQuestions(questions)
+	}
+
+	now := s.now()
+	session := &quizSession{
+		id:        hex.EncodeToString(b),
+		questions: questions,
+		records:   make([]answerRecord, 0, len(questions)),
+		startedAt: now,
+		deadline:  now.Add(s.limit),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, old := range s.sessions {
+		if now.Sub(old.deadline) > sessionRetention {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[session.id] = session
+
+	return session, nil
+}
+
+// currentQuestion returns the next unanswered question in the session and its index.
+func (s *webServer) currentQuestion(id string) (quizQuestion, int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return quizQuestion{}, 0, time.Time{}, errSessionNotFound
+	}
+	now := s.now()
+	if session.finished(now) {
+		return quizQuestion{}, 0, time.Time{}, errQuizFinished
+	}
+
+	if session.askedAt.IsZero() {
+		session.askedAt = now
+	}
+	number := len(session.records)
+
+	return session.questions[number], number, session.deadline, nil
+}
+
+// submitAnswer checks the answer to the current question in the session. Answers which arrive after the deadline
+// are rejected.
+func (s *webServer) submitAnswer(id, answer string) (answerRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return answerRecord{}, errSessionNotFound
+	}
+	now := s.now()
+	if !now.Before(session.deadline) {
+		return answerRecord{}, errTimeLimit
+	}
+	if session.finished(now) {
+		return answerRecord{}, errQuizFinished
+	}
+
+	number := len(session.records)
+	question := session.questions[number]
+	askedAt := session.askedAt
+	if askedAt.IsZero() {
+		askedAt = now
+	}
+
+	record := answerRecord{number: number, question: question, given: answer, outcome: answeredIncorrectly, duration: now.Sub(askedAt)}
+	if question.IsCorrect(answer) {
+		record.outcome = answeredCorrectly
+	}
+	session.records = append(session.records, record)
+	session.askedAt = time.Time{}
+
+	return record, nil
+}
+
+// sessionResult returns the report for a session and whether the quiz has finished.
+func (s *webServer) sessionResult(id string) (quizReport, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return quizReport{}, false, errSessionNotFound
+	}
+
+	return buildReport(s.quizFile, session.startedAt, session.questions, session.records), session.finished(s.now()), nil
+}
+
+// apiSession is the response when starting a session.
+type apiSession struct {
+	ID       string    `json:"id"`
+	Total    int       `json:"total"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// apiQuestion is the response when fetching the next question.
+type apiQuestion struct {
+	Number      int      `json:"number"`
+	Question    string   `json:"question"`
+	Choices     []string `json:"choices,omitempty"`
+	RemainingMs int64    `json:"remaining_ms"`
+}
+
+// apiAnswer is the request body when submitting an answer.
+type apiAnswer struct {
+	Answer string `json:"answer"`
+}
+
+// apiAnswerResult is the response after submitting an answer.
+type apiAnswerResult struct {
+	Correct  bool   `json:"correct"`
+	Expected string `json:"expected"`
+}
+
+// apiResult is the response when fetching the result of a session.
+type apiResult struct {
+	Finished bool       `json:"finished"`
+	Report   quizReport `json:"report"`
+}
+
+func (s *webServer) handleAPISessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	session, err := s.startSession()
+	if err != nil {
+		log.Printf("error starting session: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, errors.New("unable to start session"))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiSession{ID: session.id, Total: len(session.questions), Deadline: session.deadline})
+}
+
+func (s *webServer) handleAPISession(w http.ResponseWriter, r *http.Request) {
+	// Expect /api/sessions/{id}/{action}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sessions/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		writeJSONError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	id, action := parts[0], parts[1]
+
+	switch {
+	case action == "question" && r.Method == http.MethodGet:
+		question, number, deadline, err := s.currentQuestion(id)
+		if err != nil {
+			writeJSONError(w, statusForError(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiQuestion{Number: number + 1, Question: question.question, Choices: question.choices, RemainingMs: deadline.Sub(s.now()).Milliseconds()})
+
+	case action == "answer" && r.Method == http.MethodPost:
+		var body apiAnswer
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("decoding request body: %v", err))
+			return
+		}
+		record, err := s.submitAnswer(id, body.Answer)
+		if err != nil {
+			writeJSONError(w, statusForError(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiAnswerResult{Correct: record.outcome == answeredCorrectly, Expected: record.question.answer})
+
+	case action == "result" && r.Method == http.MethodGet:
+		report, finished, err := s.sessionResult(id)
+		if err != nil {
+			writeJSONError(w, statusForError(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiResult{Finished: finished, Report: report})
+
+	default:
+		writeJSONError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// statusForError maps the session errors to HTTP status codes.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errQuizFinished), errors.Is(err, errTimeLimit):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error writing JSON response: %v", err)
+	}
+}
+
+// writeJSONError writes err as a JSON error response.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// questionPage is the data used to render a question in the HTML front end.
+type questionPage struct {
+	ID          string
+	Number      int
+	Total       int
+	Text        string
+	Choices     []string
+	RemainingMs int64
+}
+
+func (s *webServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.render(w, "start.html", map[string]any{"QuizFile": s.quizFile, "Total": len(s.questions), "Limit": s.limit})
+}
+
+func (s *webServer) handlePlayStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, err := s.startSession()
+	if err != nil {
+		log.Printf("error starting session: %v", err)
+		http.Error(w, "Something went wrong...", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/play/"+session.id, http.StatusSeeOther)
+}
+
+func (s *webServer) handlePlay(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/play/")
+
+	if r.Method == http.MethodPost {
+		_, err := s.submitAnswer(id, r.FormValue("answer"))
+		if errors.Is(err, errSessionNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		// Late answers are simply dropped, and the redirect will show the results
+		http.Redirect(w, r, "/play/"+id, http.StatusSeeOther)
+		return
+	}
+
+	question, number, deadline, err := s.currentQuestion(id)
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		http.NotFound(w, r)
+
+	case errors.Is(err, errQuizFinished):
+		report, _, err := s.sessionResult(id)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.render(w, "result.html", report)
+
+	case err != nil:
+		http.Error(w, "Something went wrong...", http.StatusInternalServerError)
+
+	default:
+		s.render(w, "question.html", questionPage{
+			ID:          id,
+			Number:      number + 1,
+			Total:       len(s.questions),
+			Text:        question.question,
+			Choices:     question.choices,
+			RemainingMs: deadline.Sub(s.now()).Milliseconds(),
+		})
+	}
+}
+
+// render executes the named template, only printing the real error to the log.
+func (s *webServer) render(w http.ResponseWriter, name string, data any) {
+	if err := s.templates.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("error executing HTML template %s: %v", name, err)
+		http.Error(w, "Something went wrong...", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestWebServer returns a server with a fake clock, which the test can move forward with the returned function.
+func newTestWebServer(t *testing.T, limit time.Duration) (*httptest.Server, func(time.Duration)) {
+	questions := []quizQuestion{{question: "1+1", answer: "2"}, {question: "capital of France?", answer: "Paris", choices: []string{"London", "Paris"}}}
+
+	ws, err := newWebServer("problems.csv", questions, limit, false)
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ws.now = func() time.Time { return now }
+
+	server := httptest.NewServer(ws.routes())
+	t.Cleanup(server.Close)
+
+	return server, func(d time.Duration) { now = now.Add(d) }
+}
+
+// doJSON sends a request to the API and decodes the JSON response into v.
+func doJSON(t *testing.T, method, url string, body any, v any) int {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		assert.NoError(t, err)
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	if v != nil {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+
+	return resp.StatusCode
+}
+
+func Test_webServerAPI(t *testing.T) {
+	server, advance := newTestWebServer(t, 30*time.Second)
+
+	var session apiSession
+	assert.Equal(t, http.StatusCreated, doJSON(t, http.MethodPost, server.URL+"/api/sessions", nil, &session))
+	assert.Equal(t, 2, session.Total)
+	base := server.URL + "/api/sessions/" + session.ID
+
+	var question apiQuestion
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodGet, base+"/question", nil, &question))
+	assert.Equal(t, apiQuestion{Number: 1, Question: "1+1", RemainingMs: 30000}, question)
+
+	advance(2 * time.Second)
+	var answer apiAnswerResult
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodPost, base+"/answer", apiAnswer{Answer: "2"}, &answer))
+	assert.Equal(t, apiAnswerResult{Correct: true, Expected: "2"}, answer)
+
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodGet, base+"/question", nil, &question))
+	assert.Equal(t, []string{"London", "Paris"}, question.Choices)
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodPost, base+"/answer", apiAnswer{Answer: "a"}, &answer))
+	assert.False(t, answer.Correct)
+
+	var result apiResult
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodGet, base+"/result", nil, &result))
+	assert.True(t, result.Finished)
+	assert.Equal(t, 1, result.Report.Correct)
+	assert.Equal(t, int64(2000), result.Report.Questions[0].TimeTakenMs)
+
+	assert.Equal(t, http.StatusConflict, doJSON(t, http.MethodGet, base+"/question", nil, nil), "expected no more questions once the quiz has finished")
+	assert.Equal(t, http.StatusNotFound, doJSON(t, http.MethodGet, server.URL+"/api/sessions/unknown/question", nil, nil))
+}
+
+func Test_webServerEnforcesTimeLimit(t *testing.T) {
+	server, advance := newTestWebServer(t, 30*time.Second)
+
+	var session apiSession
+	doJSON(t, http.MethodPost, server.URL+"/api/sessions", nil, &session)
+	base := server.URL + "/api/sessions/" + session.ID
+
+	// The client never asks for a question, but the time limit still runs from the start of the session
+	advance(31 * time.Second)
+	assert.Equal(t, http.StatusConflict, doJSON(t, http.MethodPost, base+"/answer", apiAnswer{Answer: "2"}, nil), "expected late answers to be rejected")
+
+	var result apiResult
+	doJSON(t, http.MethodGet, base+"/result", nil, &result)
+	assert.True(t, result.Finished)
+	assert.Equal(t, 0, result.Report.Correct)
+	assert.Equal(t, "unanswered", result.Report.Questions[0].Result)
+}
+
+func Test_webServerHTML(t *testing.T) {
+	server, _ := newTestWebServer(t, 30*time.Second)
+
+	resp, err := http.PostForm(server.URL+"/play", nil)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Contains(t, string(body), "Question #1 of 2", "expected to be redirected to the first question")
+
+	playURL := resp.Request.URL.String()
+	for _, answer := range []string{"2", "b"} {
+		resp, err = http.PostForm(playURL, url.Values{"answer": {answer}})
+		assert.NoError(t, err)
+		body, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	assert.True(t, strings.Contains(string(body), "You answered 2 out of 2 correct!"), "expected the results page, got: %s", body)
+}
@@ -0,0 +1,282 @@
+package urlshort
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// statsPath is where the StatsHandler is served, so short links can't use it
+	statsPath = "/stats"
+
+	// clickBatchSize is the most clicks written to the ClickStore at once
+	clickBatchSize = 100
+	// clickQueueSize is how many clicks can be waiting to be written before new clicks are dropped, so a slow
+	// ClickStore never holds up a redirect
+	clickQueueSize = 10_000
+)
+
+// Click is a single hit on a short link.
+type Click struct {
+	Time      time.Time `json:"time"`
+	Path      string    `json:"path"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	// ClientNetwork is the network the client is in rather than its address, e.g. 203.0.113.0/24
+	ClientNetwork string `json:"client_network,omitempty"`
+}
+
+// BucketCount is the number of clicks in the time bucket starting at Start.
+type BucketCount struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// LinkStats is the clicks on a short link, counted per time bucket.
+type LinkStats struct {
+	Path    string        `json:"path"`
+	Total   int           `json:"total"`
+	Bucket  string        `json:"bucket"`
+	Buckets []BucketCount `json:"buckets"`
+}
+
+// ClickStore is somewhere the clicks are kept.
+type ClickStore interface {
+	// SaveClicks adds a batch of clicks
+	SaveClicks(ctx context.Context, clicks []Click) error
+	// Stats counts the clicks on path since the given time, in buckets of 'hour' or 'day' (in UTC)
+	Stats(ctx context.Context, path, bucket string, since time.Time) (LinkStats, error)
+}
+
+// ClickRecorder queues up clicks and writes them to a ClickStore in batches in the background, so that recording a
+// click doesn't slow down the redirect.
+type ClickRecorder struct {
+	store   ClickStore
+	clicks  chan Click
+	done    chan struct{}
+	dropped int
+	closed  bool
+	mu      sync.Mutex
+}
+
+// NewClickRecorder returns a ClickRecorder which writes to store at least every interval. Close must be called to
+// write the last batch.
+func NewClickRecorder(store ClickStore, interval time.Duration) *ClickRecorder {
+	rec := &ClickRecorder{store: store, clicks: make(chan Click, clickQueueSize), done: make(chan struct{})}
+	go rec.run(interval)
+
+	return rec
+}
+
+// Record queues a click to be written, dropping it if the queue is full or the recorder has been closed.
+func (rec *ClickRecorder) Record(c Click) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.closed {
+		return
+	}
+	select {
+	case rec.clicks <- c:
+	default:
+		rec.dropped++
+	}
+}
+
+// Close writes any queued clicks and stops the recorder.
+func (rec *ClickRecorder) Close() {
+	// The queue is closed whilst holding the lock, so a Record running at the same time can't send on it afterwards
+	rec.mu.Lock()
+	if !rec.closed {
+		rec.closed = true
+		close(rec.clicks)
+	}
+	rec.mu.Unlock()
+
+	<-rec.done
+}
+
+// Handler returns a http.Handler which records a click for every request which next redirects.
+func (rec *ClickRecorder) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		if sw.status < 300 || sw.status > 399 {
+			return
+		}
+		rec.Record(Click{
+			Time:          time.Now().UTC(),
+			Path:          r.URL.Path,
+			Referrer:      r.Referer(),
+			UserAgent:     r.UserAgent(),
+			ClientNetwork: clientNetwork(r.RemoteAddr),
+		})
+	})
+}
+
+// run writes the clicks in batches, whenever a batch is full or every interval, until the recorder is closed.
+func (rec *ClickRecorder) run(interval time.Duration) {
+	defer close(rec.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]Click, 0, clickBatchSize)
+	for {
+		select {
+		case c, ok := <-rec.clicks:
+			if !ok {
+				rec.flush(batch)
+				return
+			}
+			batch = append(batch, c)
+			if len(batch) == clickBatchSize {
+				rec.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			rec.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes a batch of clicks, logging rather than retrying if it fails as losing a few clicks is better than
+// the queue backing up.
+func (rec *ClickRecorder) flush(batch []Click) {
+	rec.mu.Lock()
+	dropped := rec.dropped
+	rec.dropped = 0
+	rec.mu.Unlock()
+	if dropped > 0 {
+		slog.Error(fmt.Sprintf("dropped %d clicks as the queue was full", dropped))
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+	if err := rec.store.SaveClicks(context.Background(), batch); err != nil {
+		slog.Error(fmt.Sprintf("unable to save %d clicks: %v", len(batch), err))
+	}
+}
+
+// statusWriter remembers the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// clientNetwork returns the /24 (IPv4) or /48 (IPv6) network of a client address, so clicks can be grouped by
+// where they came from without keeping the client's actual address.
+func clientNetwork(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%s/24", v4.Mask(net.CIDRMask(24, 32)))
+	}
+
+	return fmt.Sprintf("%s/48", ip.Mask(net.CIDRMask(48, 128)))
+}
+
+// bucketStart returns the start of the hour or day containing t, in UTC.
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	if bucket == "hour" {
+		return t.Truncate(time.Hour)
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// countBuckets counts the clicks in each bucket, oldest first.
+func countBuckets(path, bucket string, clicks []Click) LinkStats {
+	counts := make(map[time.Time]int)
+	for _, c := range clicks {
+		counts[bucketStart(c.Time, bucket)]++
+	}
+
+	stats := LinkStats{Path: path, Total: len(clicks), Bucket: bucket, Buckets: make([]BucketCount, 0, len(counts))}
+	for start, n := range counts {
+		stats.Buckets = append(stats.Buckets, BucketCount{Start: start, Count: n})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool { return stats.Buckets[i].Start.Before(stats.Buckets[j].Start) })
+
+	return stats
+}
+
+// StatsHandler serves 'GET /stats/{path}', the clicks on a short link. The optional 'bucket' query parameter counts
+// the clicks per 'hour' or 'day' (the default), and 'since' only counts clicks since an RFC 3339 time.
+//
+// As with the admin API, every request must send the API token as an 'Authorization: Bearer <token>' header.
+type StatsHandler struct {
+	store ClickStore
+	token string
+}
+
+// NewStatsHandler returns a StatsHandler which reads the clicks from store.
+func NewStatsHandler(store ClickStore, token string) *StatsHandler {
+	return &StatsHandler{store: store, token: token}
+}
+
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorised(r, h.token) {
+		unauthorised(w)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+
+	path := "/" + strings.TrimPrefix(r.URL.Path, statsPath+"/")
+	if path == "/" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "day"
+	}
+	if bucket != "hour" && bucket != "day" {
+		writeError(w, http.StatusBadRequest, "bucket must be 'hour' or 'day'")
+		return
+	}
+
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time e.g. 2024-01-02T15:04:05Z")
+			return
+		}
+	}
+
+	stats, err := h.store.Stats(r.Context(), path, bucket, since)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
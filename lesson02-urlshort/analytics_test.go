@@ -0,0 +1,183 @@
+package urlshort
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryClickStore keeps the batches of clicks it is given in memory.
+type memoryClickStore struct {
+	mu      sync.Mutex
+	batches [][]Click
+}
+
+func (s *memoryClickStore) SaveClicks(_ context.Context, clicks []Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]Click(nil), clicks...))
+
+	return nil
+}
+
+func (s *memoryClickStore) Stats(_ context.Context, path, bucket string, _ time.Time) (LinkStats, error) {
+	return LinkStats{Path: path, Bucket: bucket}, nil
+}
+
+// batchSizes returns the number of clicks in each batch saved so far.
+func (s *memoryClickStore) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make([]int, 0, len(s.batches))
+	for _, b := range s.batches {
+		sizes = append(sizes, len(b))
+	}
+
+	return sizes
+}
+
+func Test_ClickRecorderBatches(t *testing.T) {
+	store := &memoryClickStore{}
+	// The interval is long enough that only full batches are written before Close
+	rec := NewClickRecorder(store, time.Hour)
+
+	for i := 0; i < 2*clickBatchSize+50; i++ {
+		rec.Record(Click{Path: "/a"})
+	}
+	assert.Eventually(t, func() bool { return len(store.batchSizes()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int{clickBatchSize, clickBatchSize}, store.batchSizes(), "expected full batches to be written straight away")
+
+	rec.Close()
+	assert.Equal(t, []int{clickBatchSize, clickBatchSize, 50}, store.batchSizes(), "expected the last partial batch to be written on close")
+
+	rec.Record(Click{Path: "/a"})
+	rec.Close()
+	assert.Len(t, store.batchSizes(), 3, "expected clicks recorded after close to be dropped")
+}
+
+func Test_ClickRecorderInterval(t *testing.T) {
+	store := &memoryClickStore{}
+	rec := NewClickRecorder(store, 10*time.Millisecond)
+	defer rec.Close()
+
+	rec.Record(Click{Path: "/a"})
+	assert.Eventually(t, func() bool { return len(store.batchSizes()) == 1 }, time.Second, time.Millisecond, "expected a partial batch to be written after the interval")
+}
+
+func Test_ClickRecorderHandler(t *testing.T) {
+	store := &memoryClickStore{}
+	rec := NewClickRecorder(store, time.Hour)
+	handler := rec.Handler(MapHandler(map[string]string{"/a": "https://a.example"}, http.NotFoundHandler()))
+
+	r := httptest.NewRequest(http.MethodGet, "/a", nil)
+	r.Header.Set("Referer", "https://referrer.example")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	rec.Close()
+
+	assert.Len(t, store.batches, 1)
+	assert.Len(t, store.batches[0], 1, "expected only the redirect to be recorded")
+	click := store.batches[0][0]
+	assert.Equal(t, "/a", click.Path)
+	assert.Equal(t, "https://referrer.example", click.Referrer)
+	assert.Equal(t, "192.0.2.0/24", click.ClientNetwork, "expected the network of httptest's client address rather than the address")
+}
+
+func Test_clientNetwork(t *testing.T) {
+	tt := []struct {
+		remoteAddr string
+		expected   string
+	}{
+		{remoteAddr: "203.0.113.42:5123", expected: "203.0.113.0/24"},
+		{remoteAddr: "203.0.113.42", expected: "203.0.113.0/24"},
+		{remoteAddr: "[2001:db8:1234:5678::1]:443", expected: "2001:db8:1234::/48"},
+		{remoteAddr: "[::ffff:203.0.113.42]:80", expected: "203.0.113.0/24"},
+		{remoteAddr: "not an address", expected: ""},
+	}
+
+	for _, tc := range tt {
+		assert.Equal(t, tc.expected, clientNetwork(tc.remoteAddr), tc.remoteAddr)
+	}
+}
+
+func Test_countBuckets(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	clicks := []Click{
+		{Time: day.Add(25 * time.Hour)},
+		{Time: day.Add(90 * time.Minute)},
+		{Time: day.Add(10 * time.Minute)},
+		// The same instant as 01:30 UTC, which is counted in UTC
+		{Time: day.Add(90 * time.Minute).In(time.FixedZone("UTC-5", -5*60*60))},
+	}
+
+	stats := countBuckets("/a", "hour", clicks)
+	assert.Equal(t, LinkStats{Path: "/a", Total: 4, Bucket: "hour", Buckets: []BucketCount{
+		{Start: day, Count: 1},
+		{Start: day.Add(time.Hour), Count: 2},
+		{Start: day.Add(25 * time.Hour), Count: 1},
+	}}, stats)
+
+	stats = countBuckets("/a", "day", clicks)
+	assert.Equal(t, []BucketCount{{Start: day, Count: 3}, {Start: day.Add(24 * time.Hour), Count: 1}}, stats.Buckets)
+
+	assert.Equal(t, []BucketCount{}, countBuckets("/a", "day", nil).Buckets, "expected an empty list rather than null when there are no clicks")
+}
+
+func Test_StatsHandler(t *testing.T) {
+	store := NewFileClickStore(filepath.Join(t.TempDir(), "clicks.jsonl"))
+	clickedAt := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	assert.NoError(t, store.SaveClicks(context.Background(), []Click{{Time: clickedAt, Path: "/a"}, {Time: clickedAt.Add(-48 * time.Hour), Path: "/a"}}))
+	handler := NewStatsHandler(store, testToken)
+
+	tt := []struct {
+		name           string
+		method         string
+		target         string
+		token          string
+		expectedStatus int
+	}{
+		{name: "missing token", target: "/stats/a", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong token", target: "/stats/a", token: "wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong method", method: http.MethodPost, target: "/stats/a", token: testToken, expectedStatus: http.StatusMethodNotAllowed},
+		{name: "no path", target: "/stats/", token: testToken, expectedStatus: http.StatusNotFound},
+		{name: "unknown bucket", target: "/stats/a?bucket=week", token: testToken, expectedStatus: http.StatusBadRequest},
+		{name: "invalid since", target: "/stats/a?since=yesterday", token: testToken, expectedStatus: http.StatusBadRequest},
+		{name: "valid", target: "/stats/a?bucket=hour&since=2024-01-02T00:00:00Z", token: testToken, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tc.target, nil)
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/stats/a?bucket=hour&since=2024-01-02T00:00:00Z", nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	var stats LinkStats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, LinkStats{Path: "/a", Total: 1, Bucket: "hour", Buckets: []BucketCount{{Start: clickedAt.Truncate(time.Hour), Count: 1}}}, stats,
+		"expected only the click since the given time, counted by hour")
+}
@@ -0,0 +1,317 @@
+package urlshort
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// apiPrefix is where the admin API is served, so short links can't use it
+	apiPrefix = "/api/links"
+
+	defaultPerPage = 20
+	maxPerPage     = 100
+
+	// maxPathLength is the longest path which fits in the redirects table's urlpath column
+	maxPathLength = 100
+)
+
+// linkList is the response to listing the short links.
+type linkList struct {
+	Links   redirects `json:"links"`
+	Total   int       `json:"total"`
+	Page    int       `json:"page"`
+	PerPage int       `json:"per_page"`
+}
+
+// APIHandler serves the admin API for creating, listing, updating and deleting short links in a Store:
+//
+//	GET    /api/links?page=1&per_page=20  list the links, ordered by path
+//	POST   /api/links                     create a link from {"path": "/slug", "url": "https://..."}, where
+//	                                      the path is optional and generated by codes when missing
+//	GET    /api/links/{slug}              get a link
+//	PUT    /api/links/{slug}              replace a link
+//	PATCH  /api/links/{slug}              change only the fields of a link which are in the request
+//	DELETE /api/links/{slug}              delete a link
+//
+// Links can also set "status", "expires_at" and "enabled", as in the YAML and JSON config files.
+//
+// Every request must send the API token as an 'Authorization: Bearer <token>' header.
+type APIHandler struct {
+	store Store
+	codes *CodeGenerator
+	token string
+}
+
+// NewAPIHandler returns an APIHandler for store, which only accepts requests authenticated with token.
+func NewAPIHandler(store Store, codes *CodeGenerator, token string) *APIHandler {
+	return &APIHandler{store: store, codes: codes, token: token}
+}
+
+func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorised(r, h.token) {
+		unauthorised(w)
+		return
+	}
+
+	if r.URL.Path == apiPrefix || r.URL.Path == apiPrefix+"/" {
+		switch r.Method {
+		case http.MethodGet:
+			h.list(w, r)
+		case http.MethodPost:
+			h.create(w, r)
+		default:
+			methodNotAllowed(w, "GET, POST")
+		}
+		return
+	}
+
+	slug := strings.TrimPrefix(r.URL.Path, apiPrefix+"/")
+	if slug == r.URL.Path {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	path := "/" + slug
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r, path)
+	case http.MethodPut, http.MethodPatch:
+		h.update(w, r, path)
+	case http.MethodDelete:
+		h.delete(w, r, path)
+	default:
+		methodNotAllowed(w, "GET, PUT, PATCH, DELETE")
+	}
+}
+
+// authorised reports whether the request has the API token, comparing in constant time so the token can't be
+// guessed from how long the comparison takes.
+func authorised(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func unauthorised(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="urlshort"`)
+	writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+}
+
+func (h *APIHandler) list(w http.ResponseWriter, r *http.Request) {
+	page, err := queryInt(r, "page", 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	perPage, err := queryInt(r, "per_page", defaultPerPage)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	links, total, err := h.store.List(r.Context(), (page-1)*perPage, perPage)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, linkList{Links: links, Total: total, Page: page, PerPage: perPage})
+}
+
+func (h *APIHandler) create(w http.ResponseWriter, r *http.Request) {
+	var link redirect
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	link.Id = 0
+	if err := validateLink(link); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if link.Path != "" {
+		if !strings.HasPrefix(link.Path, "/") {
+			link.Path = "/" + link.Path
+		}
+		if err := validatePath(link.Path); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		created, err := h.store.Create(r.Context(), link)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeCreated(w, created)
+		return
+	}
+
+	created, err := h.codes.CreateWithCode(r.Context(), h.store, link)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeCreated(w, created)
+}
+
+func (h *APIHandler) get(w http.ResponseWriter, r *http.Request, path string) {
+	link, err := h.store.Get(r.Context(), path)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, link)
+}
+
+func (h *APIHandler) update(w http.ResponseWriter, r *http.Request, path string) {
+	var link redirect
+	if r.Method == http.MethodPatch {
+		// Decode the request over the current link, so any fields which aren't in the request are kept
+		var err error
+		if link, err = h.store.Get(r.Context(), path); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	link.Path = path
+	if err := validateLink(link); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := h.store.Update(r.Context(), link)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *APIHandler) delete(w http.ResponseWriter, r *http.Request, path string) {
+	if err := h.store.Delete(r.Context(), path); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reservedPaths are served by the server itself rather than as short links, along with every path below them.
+var reservedPaths = []string{apiPrefix, shortenPath, statsPath}
+
+// validatePath checks that a short link path is made of slug or pattern characters, fits in the database and doesn't
+// clash with the paths served by the server itself.
+func validatePath(path string) error {
+	if path == "/" {
+		return fmt.Errorf("path '%s' is reserved", path)
+	}
+	for _, reserved := range reservedPaths {
+		if path == reserved || strings.HasPrefix(path, reserved+"/") {
+			return fmt.Errorf("path '%s' is reserved", path)
+		}
+	}
+	if len(path) > maxPathLength {
+		return fmt.Errorf("path is %d characters long, but can be at most %d", len(path), maxPathLength)
+	}
+	for _, c := range strings.TrimPrefix(path, "/") {
+		if !strings.ContainsRune(DefaultCodeAlphabet+"-_./{}*", c) {
+			return fmt.Errorf("path '%s' can only contain letters, numbers, '-', '_', '.', '/' and the pattern characters '{', '}' and '*'", path)
+		}
+	}
+
+	return nil
+}
+
+// validateLink checks the target URL and status code of a link.
+func validateLink(link redirect) error {
+	if err := validateURL(link.URL); err != nil {
+		return err
+	}
+
+	return link.validate()
+}
+
+// validateURL checks that the target of a short link is an absolute http(s) URL.
+func validateURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url '%s' must be an absolute http or https URL", target)
+	}
+
+	return nil
+}
+
+// queryInt returns a positive whole number from the query string, or def when it isn't set.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive whole number", name)
+	}
+
+	return n, nil
+}
+
+func writeCreated(w http.ResponseWriter, link redirect) {
+	w.Header().Set("Location", apiPrefix+link.Path)
+	writeJSON(w, http.StatusCreated, link)
+}
+
+// writeStoreError maps the errors returned by a Store to a response.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrExists):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrReadOnly):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrNoFreeCode):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		slog.Error(fmt.Sprintf("admin API request failed: %v", err))
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error(fmt.Sprintf("unable to write response: %v", err))
+	}
+}
@@ -0,0 +1,177 @@
+package urlshort
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "secret"
+
+// newTestAPI returns an APIHandler backed by a temporary YAML file containing red, along with the file's path.
+func newTestAPI(t *testing.T, red redirects) (*APIHandler, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	store := NewFileStore(path)
+	assert.NoError(t, store.save(red))
+	codes, err := NewCodeGenerator(DefaultCodeAlphabet, DefaultCodeLength)
+	assert.NoError(t, err)
+
+	return NewAPIHandler(store, codes, testToken), path
+}
+
+// apiRequest sends a request to handler with the API token, decoding the JSON response into v when it isn't nil.
+func apiRequest(t *testing.T, handler http.Handler, method, target, body string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if v != nil {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+	}
+
+	return w
+}
+
+func Test_APIHandlerAuthorisation(t *testing.T) {
+	handler, _ := newTestAPI(t, redirects{})
+
+	tt := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "missing token", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", authorization: testToken, expectedStatus: http.StatusUnauthorized},
+		{name: "valid token", authorization: "Bearer " + testToken, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, apiPrefix, nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func Test_APIHandlerList(t *testing.T) {
+	handler, _ := newTestAPI(t, redirects{
+		{Path: "/c", URL: "https://c.example"},
+		{Path: "/a", URL: "https://a.example"},
+		{Path: "/b", URL: "https://b.example"},
+	})
+
+	var list linkList
+	w := apiRequest(t, handler, http.MethodGet, apiPrefix+"?page=2&per_page=2", "", &list)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, linkList{Links: redirects{{Path: "/c", URL: "https://c.example"}}, Total: 3, Page: 2, PerPage: 2}, list)
+
+	w = apiRequest(t, handler, http.MethodGet, apiPrefix+"?page=3&per_page=2", "", &list)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, redirects{}, list.Links, "expected an empty page after the last link")
+
+	w = apiRequest(t, handler, http.MethodGet, apiPrefix+"?per_page=1000", "", &list)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, maxPerPage, list.PerPage, "expected per_page to be capped")
+
+	for _, query := range []string{"?page=0", "?page=-1", "?page=x", "?per_page=0"} {
+		w = apiRequest(t, handler, http.MethodGet, apiPrefix+query, "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func Test_APIHandlerLinks(t *testing.T) {
+	handler, _ := newTestAPI(t, redirects{{Path: "/existing", URL: "https://existing.example"}})
+
+	var link redirect
+	w := apiRequest(t, handler, http.MethodPost, apiPrefix, `{"path": "new", "url": "https://new.example"}`, &link)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, redirect{Path: "/new", URL: "https://new.example"}, link, "expected the path to be given a leading slash")
+	assert.Equal(t, apiPrefix+"/new", w.Header().Get("Location"))
+
+	w = apiRequest(t, handler, http.MethodPost, apiPrefix, `{"path": "/existing", "url": "https://other.example"}`, nil)
+	assert.Equal(t, http.StatusConflict, w.Code, "expected a conflict when the path is already in use")
+
+	w = apiRequest(t, handler, http.MethodGet, apiPrefix+"/new", "", &link)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://new.example", link.URL)
+
+	w = apiRequest(t, handler, http.MethodPut, apiPrefix+"/new", `{"url": "https://changed.example"}`, &link)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, redirect{Path: "/new", URL: "https://changed.example"}, link)
+
+	// PATCH only changes the fields in the request, whereas PUT replaces the whole link
+	disabled := false
+	w = apiRequest(t, handler, http.MethodPatch, apiPrefix+"/new", `{"enabled": false, "status": 302}`, &link)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, redirect{Path: "/new", URL: "https://changed.example", Status: http.StatusFound, Enabled: &disabled}, link)
+
+	w = apiRequest(t, handler, http.MethodPatch, apiPrefix+"/new", `{"status": 200}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "expected a status code which isn't a redirect to be rejected")
+
+	w = apiRequest(t, handler, http.MethodDelete, apiPrefix+"/new", "", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		w = apiRequest(t, handler, method, apiPrefix+"/new", `{"url": "https://changed.example"}`, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, "expected %s of a missing link to be not found", method)
+	}
+
+	long := `{"path": "/` + strings.Repeat("a", maxPathLength) + `", "url": "https://bad.example"}`
+	for _, body := range []string{`{"path": "/bad", "url": "ftp://bad.example"}`, `{"path": "/bad space", "url": "https://bad.example"}`, `{"path": "/api/links/x", "url": "https://bad.example"}`, `{"path": "/shorten", "url": "https://bad.example"}`, `{"path": "/stats/a", "url": "https://bad.example"}`, long, `not json`} {
+		w = apiRequest(t, handler, http.MethodPost, apiPrefix, body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func Test_APIHandlerGeneratedSlug(t *testing.T) {
+	handler, _ := newTestAPI(t, redirects{})
+
+	var link redirect
+	w := apiRequest(t, handler, http.MethodPost, apiPrefix, `{"url": "https://generated.example"}`, &link)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Len(t, link.Path, DefaultCodeLength+1)
+	assert.NoError(t, validatePath(link.Path), "expected the generated path to be a valid slug")
+
+	w = apiRequest(t, handler, http.MethodGet, apiPrefix+link.Path, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code, "expected the generated link to be saved")
+}
+
+func Test_FileStoreSave(t *testing.T) {
+	for _, name := range []string{"config.yaml", "config.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			store := NewFileStore(path)
+			assert.NoError(t, store.save(redirects{{Path: "/a", URL: "https://a.example"}}))
+
+			_, err := store.Create(context.Background(), redirect{Path: "/b", URL: "https://b.example"})
+			assert.NoError(t, err)
+
+			// The config file is replaced by renaming the temporary file over it, so none is left behind
+			_, err = os.Stat(path + ".tmp")
+			assert.True(t, os.IsNotExist(err), "expected the temporary file to have been renamed over the config file")
+
+			red, err := NewFileStore(path).load()
+			assert.NoError(t, err)
+			assert.Equal(t, redirects{{Path: "/a", URL: "https://a.example"}, {Path: "/b", URL: "https://b.example"}}, red)
+		})
+	}
+}
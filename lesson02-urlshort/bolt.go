@@ -0,0 +1,245 @@
+package urlshort
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// redirectsBucket is the bolt bucket which holds the redirects, keyed by path
+var redirectsBucket = []byte("redirects")
+
+// BoltStore keeps the redirects in an embedded bolt database file, so small deployments don't need a database server.
+// Each redirect is stored as JSON under its path, so looking up a path is quick enough to do on every request.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the bolt database at path, creating it when it doesn't exist. Only one process can have the
+// file open at once, so it waits up to a few seconds for another process to close it.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt database %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(redirectsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("creating bucket in bolt database %s: %v", path, err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the database file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) All(_ context.Context) (redirects, error) {
+	return s.filter(func(redirect) bool { return true })
+}
+
+// Rules returns the pattern and prefix rules, which can't be looked up by their path.
+func (s *BoltStore) Rules(_ context.Context) (redirects, error) {
+	return s.filter(func(r redirect) bool { return r.Prefix || isPattern(r.Path) })
+}
+
+func (s *BoltStore) List(_ context.Context, offset, limit int) (redirects, int, error) {
+	red := make(redirects, 0)
+	var total int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(redirectsBucket)
+		total = b.Stats().KeyN
+
+		// The keys are kept in order, so the page can be read without decoding the redirects before it
+		c := b.Cursor()
+		k, v := c.First()
+		for i := 0; k != nil && i < offset; i++ {
+			k, v = c.Next()
+		}
+		for ; k != nil && len(red) < limit; k, v = c.Next() {
+			r, err := decodeRedirect(k, v)
+			if err != nil {
+				return err
+			}
+			red = append(red, r)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return red, total, nil
+}
+
+func (s *BoltStore) Get(_ context.Context, path string) (redirect, error) {
+	var r redirect
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(redirectsBucket).Get([]byte(path))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		var err error
+		r, err = decodeRedirect([]byte(path), v)
+		return err
+	})
+
+	return r, err
+}
+
+func (s *BoltStore) FindByURL(_ context.Context, url string) (redirect, error) {
+	now := time.Now()
+	red, err := s.filter(func(r redirect) bool { return r.URL == url && r.active(now) })
+	if err != nil {
+		return redirect{}, err
+	}
+	if len(red) == 0 {
+		return redirect{}, ErrNotFound
+	}
+
+	return red[0], nil
+}
+
+func (s *BoltStore) Create(_ context.Context, r redirect) (redirect, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		r, err = createBoltRedirect(tx.Bucket(redirectsBucket), r)
+		return err
+	})
+	if err != nil {
+		return redirect{}, err
+	}
+
+	return r, nil
+}
+
+func (s *BoltStore) Update(_ context.Context, r redirect) (redirect, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		r, err = updateBoltRedirect(tx.Bucket(redirectsBucket), r)
+		return err
+	})
+	if err != nil {
+		return redirect{}, err
+	}
+
+	return r, nil
+}
+
+// WriteBatch creates and updates the redirects in a single transaction.
+func (s *BoltStore) WriteBatch(_ context.Context, creates, updates redirects) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(redirectsBucket)
+		for _, r := range creates {
+			if _, err := createBoltRedirect(b, r); err != nil {
+				return fmt.Errorf("creating %s: %w", r.Path, err)
+			}
+		}
+		for _, r := range updates {
+			if _, err := updateBoltRedirect(b, r); err != nil {
+				return fmt.Errorf("updating %s: %w", r.Path, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) Delete(_ context.Context, path string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(redirectsBucket)
+		if b.Get([]byte(path)) == nil {
+			return ErrNotFound
+		}
+
+		return b.Delete([]byte(path))
+	})
+}
+
+// filter returns the redirects which keep returns true for, ordered by path.
+func (s *BoltStore) filter(keep func(redirect) bool) (redirects, error) {
+	red := make(redirects, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(redirectsBucket).ForEach(func(k, v []byte) error {
+			r, err := decodeRedirect(k, v)
+			if err != nil {
+				return err
+			}
+			if keep(r) {
+				red = append(red, r)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return red, nil
+}
+
+// createBoltRedirect stores r with a new id, or returns ErrExists if its path is already in use.
+func createBoltRedirect(b *bolt.Bucket, r redirect) (redirect, error) {
+	if b.Get([]byte(r.Path)) != nil {
+		return redirect{}, ErrExists
+	}
+
+	id, err := b.NextSequence()
+	if err != nil {
+		return redirect{}, fmt.Errorf("generating id: %v", err)
+	}
+	r.Id = int(id)
+
+	return r, putRedirect(b, r)
+}
+
+// updateBoltRedirect replaces the redirect with the same path as r, keeping its id, or returns ErrNotFound.
+func updateBoltRedirect(b *bolt.Bucket, r redirect) (redirect, error) {
+	v := b.Get([]byte(r.Path))
+	if v == nil {
+		return redirect{}, ErrNotFound
+	}
+
+	current, err := decodeRedirect([]byte(r.Path), v)
+	if err != nil {
+		return redirect{}, err
+	}
+	r.Id = current.Id
+
+	return r, putRedirect(b, r)
+}
+
+// putRedirect stores r under its path.
+func putRedirect(b *bolt.Bucket, r redirect) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshalling redirect: %v", err)
+	}
+
+	return b.Put([]byte(r.Path), v)
+}
+
+// decodeRedirect reads the redirect stored under key.
+func decodeRedirect(key, v []byte) (redirect, error) {
+	var r redirect
+	if err := json.Unmarshal(v, &r); err != nil {
+		return redirect{}, fmt.Errorf("reading redirect %s: %v", key, err)
+	}
+	if !bytes.Equal([]byte(r.Path), key) {
+		return redirect{}, fmt.Errorf("reading redirect %s: stored with path %s", key, r.Path)
+	}
+
+	return r, nil
+}
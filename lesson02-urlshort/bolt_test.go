@@ -0,0 +1,97 @@
+package urlshort
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestBoltStore(t *testing.T) *BoltStore {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "links.db"))
+	if err != nil {
+		t.Fatalf("opening bolt store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func Test_BoltStore(t *testing.T) {
+	ctx := context.Background()
+	store := openTestBoltStore(t)
+
+	created, err := store.Create(ctx, redirect{Path: "/b", URL: "https://b.example", Status: http.StatusFound})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created.Id)
+
+	_, err = store.Create(ctx, redirect{Path: "/b", URL: "https://other.example"})
+	assert.ErrorIs(t, err, ErrExists)
+
+	_, err = store.Create(ctx, redirect{Path: "/a", URL: "https://a.example"})
+	assert.NoError(t, err)
+	_, err = store.Create(ctx, redirect{Path: "/docs", URL: "https://docs.example", Prefix: true})
+	assert.NoError(t, err)
+
+	got, err := store.Get(ctx, "/b")
+	assert.NoError(t, err)
+	assert.Equal(t, created, got)
+
+	found, err := store.FindByURL(ctx, "https://a.example")
+	assert.NoError(t, err)
+	assert.Equal(t, "/a", found.Path)
+
+	rules, err := store.Rules(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rules))
+	assert.Equal(t, "/docs", rules[0].Path)
+
+	list, total, err := store.List(ctx, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, redirects{{Path: "/b", URL: "https://b.example", Id: 1, Status: http.StatusFound}}, list, "expected the list to be ordered by path")
+
+	updated, err := store.Update(ctx, redirect{Path: "/b", URL: "https://b2.example"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updated.Id, "expected the id to be kept")
+
+	_, err = store.Update(ctx, redirect{Path: "/missing", URL: "https://missing.example"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.Delete(ctx, "/b"))
+	assert.ErrorIs(t, store.Delete(ctx, "/b"), ErrNotFound)
+	_, err = store.Get(ctx, "/b")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_BoltStoreWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := openTestBoltStore(t)
+
+	_, err := store.Create(ctx, redirect{Path: "/docs", URL: "https://docs.example", Prefix: true})
+	assert.NoError(t, err)
+
+	handler := NewReloadingHandler(nil, http.NotFoundHandler())
+	assert.NoError(t, handler.Watch(ctx, store, time.Hour))
+
+	location := func(path string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Header().Get("Location")
+	}
+
+	assert.Equal(t, "https://docs.example/a", location("/docs/a"))
+
+	// Exact paths are looked up on each request, so they are served without a reload
+	_, err = store.Create(ctx, redirect{Path: "/new", URL: "https://new.example"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://new.example", location("/new"))
+
+	assert.NoError(t, store.Delete(ctx, "/new"))
+	assert.Equal(t, "", location("/new"))
+}
@@ -0,0 +1,373 @@
+package urlshort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MapStore is a read-only Store of redirects which are built in, such as the defaults in main.
+type MapStore struct {
+	red redirects
+}
+
+// NewMapStore returns a MapStore for pathsToUrls.
+func NewMapStore(pathsToUrls map[string]string) *MapStore {
+	red := make(redirects, 0, len(pathsToUrls))
+	for path, url := range pathsToUrls {
+		red = append(red, redirect{Path: path, URL: url})
+	}
+	sort.Slice(red, func(i, j int) bool { return red[i].Path < red[j].Path })
+
+	return &MapStore{red: red}
+}
+
+func (s *MapStore) All(_ context.Context) (redirects, error) {
+	return append(redirects{}, s.red...), nil
+}
+
+func (s *MapStore) List(_ context.Context, offset, limit int) (redirects, int, error) {
+	return page(s.red, offset, limit), len(s.red), nil
+}
+
+func (s *MapStore) Get(_ context.Context, path string) (redirect, error) {
+	if i := s.red.index(path); i >= 0 {
+		return s.red[i], nil
+	}
+
+	return redirect{}, ErrNotFound
+}
+
+func (s *MapStore) FindByURL(_ context.Context, url string) (redirect, error) {
+	for _, r := range s.red {
+		if r.URL == url {
+			return r, nil
+		}
+	}
+
+	return redirect{}, ErrNotFound
+}
+
+func (s *MapStore) Create(context.Context, redirect) (redirect, error) {
+	return redirect{}, ErrReadOnly
+}
+
+func (s *MapStore) Update(context.Context, redirect) (redirect, error) {
+	return redirect{}, ErrReadOnly
+}
+
+func (s *MapStore) Delete(context.Context, string) error {
+	return ErrReadOnly
+}
+
+func (s *MapStore) ReadOnly() bool {
+	return true
+}
+
+// indexedStore is a Store which is quick enough to look up on every request, such as a database table with an index
+// on the path, so only its pattern and prefix rules need to be kept in memory.
+type indexedStore interface {
+	Store
+	// Rules returns the pattern and prefix rules, which can't be looked up by their path
+	Rules(ctx context.Context) (redirects, error)
+}
+
+// snapshot is the redirects from each source of a Chain, ready to build a matcher.
+type snapshot struct {
+	layers []layer
+	rules  map[string]Link
+}
+
+// equal reports whether two snapshots have the same redirects.
+func (s snapshot) equal(other snapshot) bool {
+	if len(s.layers) != len(other.layers) || !mapsEqual(s.rules, other.rules) {
+		return false
+	}
+	for i, l := range s.layers {
+		if l.store != other.layers[i].store || !mapsEqual(l.links, other.layers[i].links) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Source is a named Store in a Chain, e.g. 'db' or 'file:config/team.yaml'.
+type Source struct {
+	Name  string
+	Store Store
+}
+
+// Conflict is a path which is in more than one source of a Chain.
+type Conflict struct {
+	Path string
+	// Sources are the names of the sources which have the path, in order, so the first one is used
+	Sources []string
+	// Differ is whether the sources redirect the path differently, rather than repeating the same redirect
+	Differ bool
+}
+
+func (c Conflict) String() string {
+	kind := "the same redirect"
+	if c.Differ {
+		kind = "different redirects"
+	}
+
+	return fmt.Sprintf("path '%s' has %s in %s, using %s", c.Path, kind, strings.Join(c.Sources, ", "), c.Sources[0])
+}
+
+// Chain is a Store which layers an ordered list of sources, so a path in an earlier source hides the same path in
+// any later source. For example team owned config files can be layered over a central database, with the built in
+// redirects last.
+//
+// New redirects are created in the first source which can be changed, and a redirect is updated or deleted in the
+// source which it is served from.
+type Chain struct {
+	sources []Source
+}
+
+// NewChain returns a Chain of sources, in order of precedence.
+func NewChain(sources ...Source) *Chain {
+	return &Chain{sources: sources}
+}
+
+// All returns every redirect which is served, so only the first one for each path.
+func (c *Chain) All(ctx context.Context) (redirects, error) {
+	merged := make(redirects, 0)
+	seen := make(map[string]bool)
+	for _, src := range c.sources {
+		red, err := src.Store.All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("loading redirects from %s: %v", src.Name, err)
+		}
+		for _, r := range red {
+			if seen[r.Path] {
+				continue
+			}
+			seen[r.Path] = true
+			merged = append(merged, r)
+		}
+	}
+
+	return merged, nil
+}
+
+// snapshot loads the exact paths of each source into a layer, other than for an indexedStore which is left to look
+// up each request, and merges the pattern and prefix rules of every source.
+func (c *Chain) snapshot(ctx context.Context) (snapshot, error) {
+	snap := snapshot{layers: make([]layer, 0, len(c.sources)), rules: make(map[string]Link)}
+	for _, src := range c.sources {
+		var red redirects
+		var err error
+		var l layer
+		if indexed, ok := src.Store.(indexedStore); ok {
+			red, err = indexed.Rules(ctx)
+			l.store = indexed
+		} else {
+			red, err = src.Store.All(ctx)
+		}
+		if err != nil {
+			return snapshot{}, fmt.Errorf("loading redirects from %s: %v", src.Name, err)
+		}
+
+		exact, rules := splitRules(buildMap(red))
+		if l.store == nil {
+			l.links = exact
+		}
+		snap.layers = append(snap.layers, l)
+		for path, link := range rules {
+			if _, ok := snap.rules[path]; !ok {
+				snap.rules[path] = link
+			}
+		}
+	}
+
+	return snap, nil
+}
+
+// Conflicts returns the paths which are in more than one source, ordered by path.
+func (c *Chain) Conflicts(ctx context.Context) ([]Conflict, error) {
+	byPath := make(map[string]*Conflict)
+	first := make(map[string]Link)
+	for _, src := range c.sources {
+		red, err := src.Store.All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("loading redirects from %s: %v", src.Name, err)
+		}
+		for _, r := range red {
+			conflict, ok := byPath[r.Path]
+			if !ok {
+				byPath[r.Path] = &Conflict{Path: r.Path, Sources: []string{src.Name}}
+				first[r.Path] = r.link()
+				continue
+			}
+			// A path repeated within a single source isn't a conflict between sources
+			if conflict.Sources[len(conflict.Sources)-1] != src.Name {
+				conflict.Sources = append(conflict.Sources, src.Name)
+			}
+			if !first[r.Path].equal(r.link()) {
+				conflict.Differ = true
+			}
+		}
+	}
+
+	conflicts := make([]Conflict, 0)
+	for _, conflict := range byPath {
+		if len(conflict.Sources) > 1 {
+			conflicts = append(conflicts, *conflict)
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Path < conflicts[j].Path })
+
+	return conflicts, nil
+}
+
+func (c *Chain) List(ctx context.Context, offset, limit int) (redirects, int, error) {
+	red, err := c.All(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(red, func(i, j int) bool { return red[i].Path < red[j].Path })
+
+	return page(red, offset, limit), len(red), nil
+}
+
+func (c *Chain) Get(ctx context.Context, path string) (redirect, error) {
+	_, r, err := c.find(ctx, path)
+	return r, err
+}
+
+func (c *Chain) FindByURL(ctx context.Context, url string) (redirect, error) {
+	for _, src := range c.sources {
+		r, err := src.Store.FindByURL(ctx, url)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return redirect{}, err
+		}
+		// Only return the redirect if it isn't hidden by the same path in an earlier source
+		if served, err := c.Get(ctx, r.Path); err != nil || served.URL != url {
+			continue
+		}
+		return r, nil
+	}
+
+	return redirect{}, ErrNotFound
+}
+
+func (c *Chain) Create(ctx context.Context, r redirect) (redirect, error) {
+	// The path mustn't be in any source, as it would either hide or be hidden by the existing redirect
+	if _, _, err := c.find(ctx, r.Path); !errors.Is(err, ErrNotFound) {
+		if err == nil {
+			return redirect{}, ErrExists
+		}
+		return redirect{}, err
+	}
+
+	src, ok := c.writable()
+	if !ok {
+		return redirect{}, ErrReadOnly
+	}
+
+	return src.Store.Create(ctx, r)
+}
+
+func (c *Chain) Update(ctx context.Context, r redirect) (redirect, error) {
+	src, _, err := c.find(ctx, r.Path)
+	if err != nil {
+		return redirect{}, err
+	}
+
+	return src.Store.Update(ctx, r)
+}
+
+func (c *Chain) Delete(ctx context.Context, path string) error {
+	src, _, err := c.find(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	return src.Store.Delete(ctx, path)
+}
+
+// WriteBatch creates the redirects in the first source which can be changed, and updates each redirect in the source
+// which it is served from. The redirects for each source are written together, so the batch is only all or nothing
+// when it is written to a single source, as it is by an import.
+func (c *Chain) WriteBatch(ctx context.Context, creates, updates redirects) error {
+	type batch struct {
+		creates, updates redirects
+	}
+	batches := make(map[string]*batch)
+	add := func(src Source) *batch {
+		if batches[src.Name] == nil {
+			batches[src.Name] = &batch{}
+		}
+		return batches[src.Name]
+	}
+
+	if len(creates) > 0 {
+		target, ok := c.writable()
+		if !ok {
+			return ErrReadOnly
+		}
+		for _, r := range creates {
+			// As with Create, the path mustn't be in any source
+			if _, _, err := c.find(ctx, r.Path); !errors.Is(err, ErrNotFound) {
+				if err == nil {
+					err = ErrExists
+				}
+				return fmt.Errorf("creating %s: %w", r.Path, err)
+			}
+			b := add(target)
+			b.creates = append(b.creates, r)
+		}
+	}
+	for _, r := range updates {
+		src, _, err := c.find(ctx, r.Path)
+		if err != nil {
+			return fmt.Errorf("updating %s: %w", r.Path, err)
+		}
+		b := add(src)
+		b.updates = append(b.updates, r)
+	}
+
+	for _, src := range c.sources {
+		if b := batches[src.Name]; b != nil {
+			if err := writeBatch(ctx, src.Store, b.creates, b.updates); err != nil {
+				return fmt.Errorf("writing to %s: %w", src.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writable returns the first source which redirects can be created in.
+func (c *Chain) writable() (Source, bool) {
+	for _, src := range c.sources {
+		if ro, ok := src.Store.(readOnlyStore); !ok || !ro.ReadOnly() {
+			return src, true
+		}
+	}
+
+	return Source{}, false
+}
+
+// find returns the redirect for path from the first source which has it.
+func (c *Chain) find(ctx context.Context, path string) (Source, redirect, error) {
+	for _, src := range c.sources {
+		r, err := src.Store.Get(ctx, path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Source{}, redirect{}, fmt.Errorf("loading redirects from %s: %v", src.Name, err)
+		}
+		return src, r, nil
+	}
+
+	return Source{}, redirect{}, ErrNotFound
+}
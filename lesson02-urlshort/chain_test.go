@@ -0,0 +1,174 @@
+package urlshort
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fileIndexedStore is a file-backed fake of PostgresStore, which is looked up on every request rather than loaded
+// into memory.
+type fileIndexedStore struct {
+	*FileStore
+}
+
+func (s fileIndexedStore) Rules(ctx context.Context) (redirects, error) {
+	red, err := s.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(redirects, 0)
+	for _, r := range red {
+		if r.Prefix || isPattern(r.Path) {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	return path
+}
+
+func Test_ChainWatch(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	central := NewFileStore(writeConfig(t, "central.yaml", `
+- path: /shared
+  url: https://central.example
+- path: /gh/{repo}
+  url: https://github.com/central/{repo}
+`))
+	team := NewFileStore(writeConfig(t, "team.json", `[
+  {"path": "/shared", "url": "https://team.example"},
+  {"path": "/gh/{repo}", "url": "https://github.com/team/{repo}"},
+  {"path": "/team", "url": "https://team.example/only"}
+]`))
+	chain := NewChain(
+		Source{Name: "central", Store: central},
+		Source{Name: "team", Store: team},
+		Source{Name: "builtin", Store: NewMapStore(map[string]string{"/builtin": "https://builtin.example", "/team": "https://builtin.example/team"})},
+	)
+
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := NewReloadingHandler(nil, fallback)
+	assert.NoError(t, handler.Watch(ctx, chain, interval))
+
+	assert.Equal(t, "https://central.example", location(handler, "/shared"), "expected the first source to win")
+	assert.Equal(t, "https://github.com/central/urlshort", location(handler, "/gh/urlshort"), "expected the first source's pattern to win")
+	assert.Equal(t, "https://team.example/only", location(handler, "/team"))
+	assert.Equal(t, "https://builtin.example", location(handler, "/builtin"))
+
+	// New redirects are created in the first source, and served once the chain is reloaded
+	_, err := chain.Create(ctx, redirect{Path: "/new", URL: "https://central.example/new"})
+	assert.NoError(t, err)
+	created, err := central.Get(ctx, "/new")
+	assert.NoError(t, err, "expected the redirect to be created in the first source")
+	assert.Equal(t, "https://central.example/new", created.URL)
+	assert.Eventually(t, func() bool { return location(handler, "/new") == "https://central.example/new" }, time.Second, interval)
+
+	// A redirect added to an earlier source hides the same path in a later one
+	_, err = central.Create(ctx, redirect{Path: "/builtin", URL: "https://central.example/builtin"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return location(handler, "/builtin") == "https://central.example/builtin" }, time.Second, interval)
+
+	_, err = chain.Create(ctx, redirect{Path: "/team", URL: "https://central.example/team"})
+	assert.ErrorIs(t, err, ErrExists, "expected a path in any source to already exist")
+
+	// Changes are made in the source which the redirect is served from
+	_, err = chain.Update(ctx, redirect{Path: "/team", URL: "https://team.example/changed"})
+	assert.NoError(t, err)
+	changed, err := team.Get(ctx, "/team")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://team.example/changed", changed.URL)
+}
+
+func Test_ChainWatchIndexed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	indexed := fileIndexedStore{NewFileStore(writeConfig(t, "db.yaml", `
+- path: /shared
+  url: https://db.example
+- path: /gh/{repo}
+  url: https://github.com/db/{repo}
+`))}
+	team := NewFileStore(writeConfig(t, "team.yaml", `
+- path: /shared
+  url: https://team.example
+- path: /team
+  url: https://team.example/only
+`))
+	chain := NewChain(
+		Source{Name: "db", Store: indexed},
+		Source{Name: "team", Store: team},
+		Source{Name: "builtin", Store: NewMapStore(map[string]string{"/builtin": "https://builtin.example"})},
+	)
+
+	handler := NewReloadingHandler(nil, http.NotFoundHandler())
+	// Use a long interval, so any changes which are served must have been looked up on the request
+	assert.NoError(t, handler.Watch(ctx, chain, time.Hour))
+
+	assert.Equal(t, "https://db.example", location(handler, "/shared"), "expected the first source to win")
+	assert.Equal(t, "https://github.com/db/urlshort", location(handler, "/gh/urlshort"))
+	assert.Equal(t, "https://team.example/only", location(handler, "/team"))
+
+	// A new redirect in the indexed store is served straight away, and so is one which hides a later source
+	_, err := chain.Create(ctx, redirect{Path: "/new", URL: "https://db.example/new"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://db.example/new", location(handler, "/new"))
+
+	_, err = indexed.Create(ctx, redirect{Path: "/builtin", URL: "https://db.example/builtin"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://db.example/builtin", location(handler, "/builtin"))
+}
+
+func Test_ChainConflicts(t *testing.T) {
+	first := NewFileStore(writeConfig(t, "first.yaml", `
+- path: /same
+  url: https://same.example
+- path: /different
+  url: https://first.example
+- path: /first
+  url: https://first.example/only
+`))
+	second := NewMapStore(map[string]string{"/same": "https://same.example", "/different": "https://second.example"})
+
+	conflicts, err := NewChain(Source{Name: "first", Store: first}, Source{Name: "second", Store: second}).Conflicts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Conflict{
+		{Path: "/different", Sources: []string{"first", "second"}, Differ: true},
+		{Path: "/same", Sources: []string{"first", "second"}},
+	}, conflicts)
+	assert.Equal(t, "path '/different' has different redirects in first, second, using first", conflicts[0].String())
+}
+
+func Test_ChainReadOnly(t *testing.T) {
+	chain := NewChain(Source{Name: "builtin", Store: NewMapStore(map[string]string{"/builtin": "https://builtin.example"})})
+
+	_, err := chain.Create(context.Background(), redirect{Path: "/new", URL: "https://new.example"})
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, chain.Delete(context.Background(), "/builtin"), ErrReadOnly)
+
+	// New redirects go in the first source which isn't read-only
+	file := NewFileStore(writeConfig(t, "links.yaml", "[]"))
+	chain = NewChain(Source{Name: "builtin", Store: NewMapStore(nil)}, Source{Name: "file", Store: file})
+	_, err = chain.Create(context.Background(), redirect{Path: "/new", URL: "https://new.example"})
+	assert.NoError(t, err)
+	_, err = file.Get(context.Background(), "/new")
+	assert.NoError(t, err)
+}
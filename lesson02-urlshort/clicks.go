@@ -0,0 +1,138 @@
+package urlshort
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileClickStore keeps the clicks in a JSON Lines file, for when the redirects aren't in the database. Every click
+// is appended to the end of the file, and the whole file is read to work out the stats.
+type FileClickStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileClickStore returns a FileClickStore which uses the file at path, creating it when the first clicks are saved.
+func NewFileClickStore(path string) *FileClickStore {
+	return &FileClickStore{path: path}
+}
+
+func (s *FileClickStore) SaveClicks(_ context.Context, clicks []Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening clicks file %s: %v", s.path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, c := range clicks {
+		if err = enc.Encode(c); err != nil {
+			return fmt.Errorf("writing clicks file %s: %v", s.path, err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("writing clicks file %s: %v", s.path, err)
+	}
+
+	return nil
+}
+
+func (s *FileClickStore) Stats(_ context.Context, path, bucket string, since time.Time) (LinkStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clicks := make([]Click, 0)
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return countBuckets(path, bucket, clicks), nil
+	}
+	if err != nil {
+		return LinkStats{}, fmt.Errorf("opening clicks file %s: %v", s.path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var c Click
+		if err = json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return LinkStats{}, fmt.Errorf("reading clicks file %s line %d: %v", s.path, line, err)
+		}
+		if c.Path == path && !c.Time.Before(since) {
+			clicks = append(clicks, c)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return LinkStats{}, fmt.Errorf("reading clicks file %s: %v", s.path, err)
+	}
+
+	return countBuckets(path, bucket, clicks), nil
+}
+
+// PostgresClickStore keeps the clicks in the Postgres clicks table, alongside the redirects table.
+type PostgresClickStore struct {
+	db *sql.DB
+}
+
+// NewPostgresClickStore returns a PostgresClickStore which uses db.
+func NewPostgresClickStore(db *sql.DB) *PostgresClickStore {
+	return &PostgresClickStore{db: db}
+}
+
+func (s *PostgresClickStore) SaveClicks(ctx context.Context, clicks []Click) error {
+	// Insert the whole batch with a single statement
+	values := make([]string, 0, len(clicks))
+	args := make([]any, 0, len(clicks)*5)
+	for i, c := range clicks {
+		n := i * 5
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, c.Time, c.Path, c.Referrer, c.UserAgent, c.ClientNetwork)
+	}
+
+	query := "INSERT INTO clicks (clicked_at, urlpath, referrer, user_agent, client_network) VALUES " + strings.Join(values, ", ")
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("inserting clicks: %v", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresClickStore) Stats(ctx context.Context, path, bucket string, since time.Time) (LinkStats, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT date_trunc($2, clicked_at AT TIME ZONE 'UTC') AS bucket, COUNT(*)
+		 FROM clicks WHERE urlpath = $1 AND clicked_at >= $3
+		 GROUP BY bucket ORDER BY bucket`, path, bucket, since)
+	if err != nil {
+		return LinkStats{}, fmt.Errorf("querying clicks: %v", err)
+	}
+	defer rows.Close()
+
+	stats := LinkStats{Path: path, Bucket: bucket, Buckets: make([]BucketCount, 0)}
+	for rows.Next() {
+		var b BucketCount
+		if err := rows.Scan(&b.Start, &b.Count); err != nil {
+			return LinkStats{}, fmt.Errorf("reading clicks: %v", err)
+		}
+		b.Start = time.Date(b.Start.Year(), b.Start.Month(), b.Start.Day(), b.Start.Hour(), 0, 0, 0, time.UTC)
+		stats.Buckets = append(stats.Buckets, b)
+		stats.Total += b.Count
+	}
+	if err := rows.Err(); err != nil {
+		return LinkStats{}, fmt.Errorf("reading clicks: %v", err)
+	}
+
+	return stats, nil
+}
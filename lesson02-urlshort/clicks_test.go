@@ -0,0 +1,37 @@
+package urlshort
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileClickStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clicks.jsonl")
+	store := NewFileClickStore(path)
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	stats, err := store.Stats(context.Background(), "/a", "day", time.Time{})
+	assert.NoError(t, err, "expected no error before any clicks have been saved")
+	assert.Equal(t, LinkStats{Path: "/a", Bucket: "day", Buckets: []BucketCount{}}, stats)
+
+	// Clicks are appended to the file by each batch
+	assert.NoError(t, store.SaveClicks(context.Background(), []Click{{Time: day.Add(time.Hour), Path: "/a"}, {Time: day.Add(2 * time.Hour), Path: "/b"}}))
+	assert.NoError(t, store.SaveClicks(context.Background(), []Click{{Time: day.Add(26 * time.Hour), Path: "/a", ClientNetwork: "203.0.113.0/24"}}))
+
+	stats, err = store.Stats(context.Background(), "/a", "day", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, LinkStats{Path: "/a", Total: 2, Bucket: "day", Buckets: []BucketCount{{Start: day, Count: 1}, {Start: day.Add(24 * time.Hour), Count: 1}}}, stats)
+
+	stats, err = store.Stats(context.Background(), "/a", "hour", day.Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []BucketCount{{Start: day.Add(26 * time.Hour), Count: 1}}, stats.Buckets, "expected only the clicks since the given time")
+
+	assert.NoError(t, os.WriteFile(path, []byte("not json\n"), 0o644))
+	_, err = store.Stats(context.Background(), "/a", "day", time.Time{})
+	assert.Error(t, err, "expected an error when the clicks file is corrupt")
+}
@@ -4,4 +4,18 @@ go 1.20
 
 require gopkg.in/yaml.v3 v3.0.1
 
-require github.com/lib/pq v1.10.9 // indirect
+require (
+	github.com/golang-migrate/migrate/v4 v4.16.2
+	github.com/lib/pq v1.10.9
+	github.com/stretchr/testify v1.8.4
+	go.etcd.io/bbolt v1.3.9
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/hashicorp/errwrap v1.1.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+)
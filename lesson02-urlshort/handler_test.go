@@ -0,0 +1,90 @@
+package urlshort
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LinkHandler(t *testing.T) {
+	links := map[string]Link{
+		"/exact":    {URL: "https://exact.example", Status: http.StatusMovedPermanently, Enabled: true},
+		"/temp":     {URL: "https://temp.example", Status: http.StatusTemporaryRedirect, Enabled: true},
+		"/expired":  {URL: "https://expired.example", Status: http.StatusFound, Enabled: true, ExpiresAt: time.Now().Add(-time.Minute)},
+		"/expiring": {URL: "https://expiring.example", Status: http.StatusFound, Enabled: true, ExpiresAt: time.Now().Add(time.Hour)},
+		"/disabled": {URL: "https://disabled.example", Status: http.StatusFound},
+
+		"/gh/{repo}":    {URL: "https://github.com/org/{repo}", Status: http.StatusFound, Enabled: true},
+		"/gh/special":   {URL: "https://special.example", Status: http.StatusFound, Enabled: true},
+		"/files/{p...}": {URL: "https://cdn.example/{p}", Status: http.StatusFound, Enabled: true},
+		"/docs":         {URL: "https://docs.example/v2/", Status: http.StatusFound, Enabled: true, Prefix: true, PassQuery: true},
+		"/docs/old":     {URL: "https://legacy.example", Status: http.StatusFound, Enabled: true, Prefix: true},
+		"/x/*/y":        {URL: "https://wild.example", Status: http.StatusFound, Enabled: true},
+	}
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := LinkHandler(links, fallback)
+
+	tt := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{name: "exact path", path: "/exact", expectedStatus: http.StatusMovedPermanently, expectedLocation: "https://exact.example"},
+		{name: "per link status", path: "/temp", expectedStatus: http.StatusTemporaryRedirect, expectedLocation: "https://temp.example"},
+		{name: "expired link", path: "/expired", expectedStatus: http.StatusGone},
+		{name: "link which hasn't expired yet", path: "/expiring", expectedStatus: http.StatusFound, expectedLocation: "https://expiring.example"},
+		{name: "disabled link", path: "/disabled", expectedStatus: http.StatusNotFound},
+		{name: "named capture", path: "/gh/urlshort", expectedStatus: http.StatusFound, expectedLocation: "https://github.com/org/urlshort"},
+		{name: "literal beats capture", path: "/gh/special", expectedStatus: http.StatusFound, expectedLocation: "https://special.example"},
+		{name: "rest capture is escaped", path: "/files/a/b%20c.txt", expectedStatus: http.StatusFound, expectedLocation: "https://cdn.example/a/b%20c.txt"},
+		{name: "prefix keeps the rest of the path and query", path: "/docs/a/b?q=1", expectedStatus: http.StatusFound, expectedLocation: "https://docs.example/v2/a/b?q=1"},
+		{name: "prefix matches its own path", path: "/docs", expectedStatus: http.StatusFound, expectedLocation: "https://docs.example/v2/"},
+		{name: "longest prefix wins", path: "/docs/old/page", expectedStatus: http.StatusFound, expectedLocation: "https://legacy.example/page"},
+		{name: "prefix only matches whole segments", path: "/docsy", expectedStatus: http.StatusTeapot},
+		{name: "wildcard", path: "/x/anything/y", expectedStatus: http.StatusFound, expectedLocation: "https://wild.example"},
+		{name: "capture doesn't match an empty segment", path: "/gh/", expectedStatus: http.StatusTeapot},
+		{name: "unknown path falls back", path: "/unknown", expectedStatus: http.StatusTeapot},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func Test_redirectLink(t *testing.T) {
+	expires := time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+	disabled := false
+
+	assert.Equal(t, Link{URL: "https://a.example", Status: http.StatusMovedPermanently, Enabled: true}, redirect{Path: "/a", URL: "https://a.example"}.link(), "expected a permanent, enabled link by default")
+	assert.Equal(t, Link{URL: "https://a.example", Status: http.StatusFound, ExpiresAt: expires}, redirect{Path: "/a", URL: "https://a.example", Status: http.StatusFound, ExpiresAt: &expires, Enabled: &disabled}.link())
+
+	assert.True(t, redirect{ExpiresAt: &expires}.active(expires.Add(-time.Second)))
+	assert.False(t, redirect{ExpiresAt: &expires}.active(expires), "expected the redirect to stop working when it expires")
+	assert.False(t, redirect{Enabled: &disabled}.active(expires))
+}
+
+func Test_redirectValidate(t *testing.T) {
+	assert.NoError(t, redirect{Path: "/a", URL: "https://a.example"}.validate())
+	assert.NoError(t, redirect{Path: "/a", URL: "https://a.example", Status: http.StatusPermanentRedirect}.validate())
+
+	assert.Error(t, redirect{Path: "/a", URL: "https://a.example", Status: http.StatusOK}.validate(), "expected only 3xx status codes")
+	assert.Error(t, redirect{Path: "/a", URL: "https://a.example", Status: http.StatusMultipleChoices}.validate(), "expected only the redirect status codes")
+
+	assert.NoError(t, redirect{Path: "/gh/{repo}", URL: "https://github.com/{repo}"}.validate())
+	assert.Error(t, redirect{Path: "/gh/{repo}", URL: "https://github.com/{org}"}.validate(), "expected placeholders to be captured")
+	assert.Error(t, redirect{Path: "/gh/x{repo}", URL: "https://github.com/"}.validate(), "expected captures to be whole segments")
+	assert.Error(t, redirect{Path: "/f/{p...}/x", URL: "https://f.example"}.validate(), "expected {name...} to be last")
+	assert.Error(t, redirect{Path: "/f/{p...}", URL: "https://f.example", Prefix: true}.validate(), "expected prefix rules not to end with {name...}")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"urlshort_lesson02"
+)
+
+// runTransfer runs the export or import command, which copy the redirects between a store and a YAML, JSON or CSV
+// file. args are the command line arguments after the command.
+func runTransfer(command string, args []string) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	from := fs.String("from", "", "export: the store to export from: db, file:<path> or bolt:<path>\nimport: the format of the file: yaml, json or csv (default detected from the -file extension)")
+	to := fs.String("to", "", "export: the format to write: yaml, json or csv (default detected from the -file extension)\nimport: the store to import into: db, file:<path> or bolt:<path>")
+	file := fs.String("file", "", "export: the file to write to (default stdout)\nimport: the file to read from (default stdin)")
+	merge := fs.String("merge", string(urlshort.MergeFail), "import: what to do with a path which already has a different redirect: skip, overwrite or fail")
+	dryRun := fs.Bool("dry-run", false, "import: report what would be imported without changing the store")
+	dbURL := fs.String("db-url", os.Getenv("DATABASE_URL"), "the Postgres connection string (default $DATABASE_URL)")
+	dbMigrate := fs.Bool("db-migrate", true, "bring the database schema up to date before importing or exporting")
+	_ = fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	storeSpec, format := *from, *to
+	if command == "import" {
+		storeSpec, format = *to, *from
+	}
+	if storeSpec == "" || strings.TrimSpace(storeSpec) == "builtin" {
+		return fmt.Errorf("the store to %s must be set to db, file:<path> or bolt:<path> e.g. bolt:./links.db", command)
+	}
+	if format == "" {
+		format = urlshort.FormatFor(*file)
+	}
+	if format == "" {
+		return fmt.Errorf("the format must be set to yaml, json or csv, or detected from the extension of -file")
+	}
+	strategy, err := urlshort.ParseMergeStrategy(*merge)
+	if err != nil {
+		return err
+	}
+
+	src, err := openSources([]string{storeSpec}, nil, urlshort.DBConfig{URL: *dbURL}, *dbMigrate, "")
+	if err != nil {
+		return err
+	}
+	defer src.close()
+
+	ctx := context.Background()
+	if command == "export" {
+		return exportRedirects(ctx, src.store, *file, format)
+	}
+
+	return importRedirects(ctx, src.store, *file, format, strategy, *dryRun)
+}
+
+// exportRedirects writes the redirects in store to the file at path, or stdout when path is empty. The file is only
+// replaced once the export has been written in full, so a failed export leaves any existing file as it was.
+func exportRedirects(ctx context.Context, store urlshort.Store, path, format string) error {
+	if path == "" {
+		n, err := urlshort.ExportRedirects(ctx, store, os.Stdout, format)
+		if err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Exported %d redirect(s)", n))
+		return nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating file for %s: %v", path, err)
+	}
+	// Removing the temporary file after it has been renamed does nothing
+	defer os.Remove(f.Name())
+	if err = f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("creating file for %s: %v", path, err)
+	}
+
+	n, err := urlshort.ExportRedirects(ctx, store, f, format)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("writing file %s: %v", f.Name(), err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("replacing file %s: %v", path, err)
+	}
+	slog.Info(fmt.Sprintf("Exported %d redirect(s) to %s", n, path))
+
+	return nil
+}
+
+// importRedirects adds the redirects in the file at path, or stdin when path is empty, to store and logs what was
+// imported.
+func importRedirects(ctx context.Context, store urlshort.Store, path, format string, merge urlshort.MergeStrategy, dryRun bool) error {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening file %s: %v", path, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	report, err := urlshort.ImportRedirects(ctx, store, r, format, merge, dryRun)
+	for _, p := range report.Duplicates {
+		slog.Warn(fmt.Sprintf("path '%s' is listed more than once, only the first is imported", p))
+	}
+	for _, p := range report.Conflicts {
+		slog.Warn(fmt.Sprintf("path '%s' already has a different redirect (merge: %s)", p, merge))
+	}
+	if err != nil {
+		return err
+	}
+
+	prefix := "Imported"
+	if dryRun {
+		prefix = "Dry run, would have imported"
+	}
+	slog.Info(fmt.Sprintf("%s: %d created, %d updated, %d unchanged, %d skipped", prefix,
+		len(report.Created), len(report.Updated), len(report.Unchanged), len(report.Skipped)))
+
+	return nil
+}
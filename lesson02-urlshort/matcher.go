@@ -0,0 +1,410 @@
+package urlshort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderPattern matches the {name} placeholders in a target URL
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// captureNamePattern is the allowed names for captured segments
+var captureNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// isPattern reports whether a path has wildcards or named captures, rather than only matching itself.
+func isPattern(path string) bool {
+	return strings.ContainsAny(path, "{}*")
+}
+
+// segmentKind is how a segment of a path pattern is matched.
+type segmentKind int
+
+const (
+	// literalSegment only matches itself
+	literalSegment segmentKind = iota
+	// paramSegment matches any single segment, which is captured when it is named e.g. {repo}, or not for *
+	paramSegment
+	// restSegment matches the rest of the path e.g. {path...}, and must be the last segment
+	restSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	value string // the literal, or the name of the capture
+}
+
+// parsePattern splits a path pattern into its segments, checking that captures are whole segments with unique names
+// and that a {name...} capture is last.
+func parsePattern(path string) ([]segment, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path '%s' must start with '/'", path)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	segments := make([]segment, 0, len(parts))
+	names := make(map[string]bool)
+	for i, p := range parts {
+		switch {
+		case p == "*":
+			segments = append(segments, segment{kind: paramSegment})
+			continue
+		case !strings.ContainsAny(p, "{}*"):
+			segments = append(segments, segment{kind: literalSegment, value: p})
+			continue
+		case !strings.HasPrefix(p, "{") || !strings.HasSuffix(p, "}"):
+			return nil, fmt.Errorf("path '%s' has '%s', but wildcards and captures must be a whole segment", path, p)
+		}
+
+		name, rest := strings.CutSuffix(p[1:len(p)-1], "...")
+		if !captureNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("path '%s' has an invalid capture '%s', names can only contain letters, numbers and '_'", path, p)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("path '%s' captures '%s' more than once", path, name)
+		}
+		names[name] = true
+
+		if rest {
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("path '%s' has '%s', which must be the last segment", path, p)
+			}
+			segments = append(segments, segment{kind: restSegment, value: name})
+			continue
+		}
+		segments = append(segments, segment{kind: paramSegment, value: name})
+	}
+
+	return segments, nil
+}
+
+// ruleSegments parses the path of a pattern or prefix rule.
+func ruleSegments(path string, prefix bool) ([]segment, error) {
+	segments, err := parsePattern(path)
+	if err != nil {
+		return nil, err
+	}
+	if prefix && segments[len(segments)-1].kind == restSegment {
+		return nil, fmt.Errorf("prefix path '%s' can't also end with a {name...} capture", path)
+	}
+
+	return segments, nil
+}
+
+// validateTarget checks that every {name} placeholder in the target URL is captured by the path.
+func validateTarget(path, target string, segments []segment) error {
+	names := make(map[string]bool)
+	for _, s := range segments {
+		if s.kind != literalSegment {
+			names[s.value] = true
+		}
+	}
+	for _, m := range placeholderPattern.FindAllStringSubmatch(target, -1) {
+		if !names[m[1]] {
+			return fmt.Errorf("url '%s' uses '{%s}', which isn't captured by path '%s'", target, m[1], path)
+		}
+	}
+
+	return nil
+}
+
+// rule is a Link along with the names of the segments it captures, in order.
+type rule struct {
+	path  string
+	link  Link
+	names []string
+}
+
+// node is a segment in the tree of pattern and prefix rules.
+type node struct {
+	literals map[string]*node
+	param    *node
+	// terminal is the rule for paths which end at this node
+	terminal *rule
+	// rest is the rule for paths which carry on past this node, from a {name...} capture or a prefix rule
+	rest *rule
+}
+
+// layer is the exact paths from one source of redirects. They are either all held in links, or looked up in store
+// on each request when store is set.
+type layer struct {
+	links map[string]Link
+	store Store
+}
+
+// lookup returns the Link for exactly path.
+func (l layer) lookup(ctx context.Context, path string) (Link, bool, error) {
+	if l.store == nil {
+		link, ok := l.links[path]
+		return link, ok, nil
+	}
+
+	r, err := l.store.Get(ctx, path)
+	if errors.Is(err, ErrNotFound) {
+		return Link{}, false, nil
+	}
+	if err != nil {
+		return Link{}, false, err
+	}
+	// A pattern or prefix rule is in the tree instead, and only matches a path which happens to be the same as its
+	// pattern through the tree
+	if r.Prefix || isPattern(r.Path) {
+		return Link{}, false, nil
+	}
+
+	return r.link(), true, nil
+}
+
+// matcher finds the Link for a request path. Exact paths are looked up in a map (or in an indexed Store), and
+// pattern and prefix rules in a tree of path segments, so matching takes the same time however many rules there are.
+//
+// An exact path always wins, checking each layer in order. Otherwise the most specific rule wins, comparing from the
+// first segment: a literal segment beats a single segment wildcard or capture, which beats a {name...} capture or the
+// remainder of a prefix rule. So the longest matching prefix rule is used when several match.
+type matcher struct {
+	layers []layer
+	root   *node
+}
+
+// newMatcher builds a matcher for links, logging and skipping any invalid patterns (which are normally caught when
+// the redirects are loaded) and any rules which clash with another rule.
+func newMatcher(links map[string]Link) *matcher {
+	m, errs := buildMatcher(links)
+	for _, err := range errs {
+		slog.Error(fmt.Sprintf("skipping redirect: %v", err))
+	}
+
+	return m
+}
+
+// buildMatcher builds a matcher for links, returning an error for every rule which couldn't be added.
+func buildMatcher(links map[string]Link) (*matcher, []error) {
+	exact, rules := splitRules(links)
+	m := &matcher{layers: []layer{{links: exact}}, root: &node{}}
+
+	return m, m.addRules(rules)
+}
+
+// newLayeredMatcher builds a matcher which checks the exact paths in each layer in order, and then the pattern and
+// prefix rules, logging and skipping any rules which can't be added as newMatcher does.
+func newLayeredMatcher(layers []layer, rules map[string]Link) *matcher {
+	m := &matcher{layers: layers, root: &node{}}
+	for _, err := range m.addRules(rules) {
+		slog.Error(fmt.Sprintf("skipping redirect: %v", err))
+	}
+
+	return m
+}
+
+// splitRules splits links into the exact paths and the pattern and prefix rules.
+func splitRules(links map[string]Link) (map[string]Link, map[string]Link) {
+	exact := make(map[string]Link)
+	rules := make(map[string]Link)
+	for path, link := range links {
+		if link.Prefix || isPattern(path) {
+			rules[path] = link
+		} else {
+			exact[path] = link
+		}
+	}
+
+	return exact, rules
+}
+
+// addRules puts the pattern and prefix rules into the tree, returning an error for every rule which couldn't be
+// added. The rules are added in order of their paths, so that when two rules clash it is always the same one which
+// is left out.
+func (m *matcher) addRules(rules map[string]Link) []error {
+	paths := make([]string, 0, len(rules))
+	for path := range rules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		if err := m.add(path, rules[path]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// add puts a pattern or prefix rule into the tree. Rules which would match exactly the same paths, such as
+// '/a/{x}' and '/a/*', clash as neither is more specific, so the second one is rejected.
+func (m *matcher) add(path string, link Link) error {
+	segments, err := ruleSegments(path, link.Prefix)
+	if err != nil {
+		return err
+	}
+	// A prefix rule of "/docs/" is the same as "/docs"
+	if link.Prefix && segments[len(segments)-1] == (segment{kind: literalSegment}) {
+		segments = segments[:len(segments)-1]
+	}
+
+	r := &rule{path: path, link: link}
+	n := m.root
+	for _, s := range segments {
+		switch s.kind {
+		case literalSegment:
+			if n.literals == nil {
+				n.literals = make(map[string]*node)
+			}
+			if n.literals[s.value] == nil {
+				n.literals[s.value] = &node{}
+			}
+			n = n.literals[s.value]
+		case paramSegment:
+			if n.param == nil {
+				n.param = &node{}
+			}
+			n = n.param
+			r.names = append(r.names, s.value)
+		case restSegment:
+			if n.rest != nil {
+				return r.clash(n.rest)
+			}
+			r.names = append(r.names, s.value)
+			n.rest = r
+			return nil
+		}
+	}
+
+	if n.terminal != nil {
+		return r.clash(n.terminal)
+	}
+	if link.Prefix && n.rest != nil {
+		return r.clash(n.rest)
+	}
+	n.terminal = r
+	if link.Prefix {
+		// The remainder of a prefix rule is captured without a name
+		r.names = append(r.names, "")
+		n.rest = r
+	}
+
+	return nil
+}
+
+// clash returns the error for a rule which matches the same paths as other.
+func (r *rule) clash(other *rule) error {
+	return fmt.Errorf("path '%s' matches the same requests as '%s'", r.path, other.path)
+}
+
+// match returns the Link for a request path, and the URL to redirect to.
+func (m *matcher) match(ctx context.Context, path string) (Link, string, bool, error) {
+	for _, l := range m.layers {
+		link, ok, err := l.lookup(ctx, path)
+		if err != nil {
+			return Link{}, "", false, err
+		}
+		if ok {
+			return link, link.URL, true, nil
+		}
+	}
+
+	r, values := m.root.match(strings.Split(strings.TrimPrefix(path, "/"), "/"), nil)
+	if r == nil {
+		return Link{}, "", false, nil
+	}
+
+	return r.link, r.target(values), true, nil
+}
+
+// match walks the tree to find the most specific rule for the remaining segments, and the values it captures.
+func (n *node) match(segments []string, values []string) (*rule, []string) {
+	if len(segments) == 0 {
+		if n.terminal != nil {
+			// A prefix rule matching its own path also captures an empty remainder
+			if len(n.terminal.names) > len(values) {
+				return n.terminal, append(values, "")
+			}
+			return n.terminal, values
+		}
+		if n.rest != nil {
+			return n.rest, append(values, "")
+		}
+		return nil, nil
+	}
+
+	if child := n.literals[segments[0]]; child != nil {
+		if r, v := child.match(segments[1:], values); r != nil {
+			return r, v
+		}
+	}
+	if n.param != nil && segments[0] != "" {
+		if r, v := n.param.match(segments[1:], append(values, segments[0])); r != nil {
+			return r, v
+		}
+	}
+	if n.rest != nil {
+		return n.rest, append(values, strings.Join(segments, "/"))
+	}
+
+	return nil, nil
+}
+
+// target returns the URL to redirect to, filling in the captured values. For a prefix rule the remainder of the
+// request path is added to the end of the URL's path.
+func (r *rule) target(values []string) string {
+	captured := make(map[string]string, len(values))
+	var remainder string
+	for i, name := range r.names {
+		if name == "" {
+			remainder = values[i]
+			continue
+		}
+		captured[name] = escapePath(values[i])
+	}
+
+	target := placeholderPattern.ReplaceAllStringFunc(r.link.URL, func(p string) string {
+		return captured[p[1:len(p)-1]]
+	})
+	if !r.link.Prefix || remainder == "" {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + remainder
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// escapePath escapes each segment of a captured path, keeping the '/' between them.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// withQuery adds the query string of the request to the target URL, after any query the target already has.
+func withQuery(target, rawQuery string) string {
+	if rawQuery == "" {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	if u.RawQuery == "" {
+		u.RawQuery = rawQuery
+	} else {
+		u.RawQuery += "&" + rawQuery
+	}
+
+	return u.String()
+}
@@ -0,0 +1,80 @@
+package urlshort
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_matcherClashes(t *testing.T) {
+	link := func(url string, prefix bool) Link {
+		return Link{URL: url, Status: http.StatusFound, Enabled: true, Prefix: prefix}
+	}
+
+	tt := []struct {
+		name        string
+		links       map[string]Link
+		path        string
+		expectedURL string
+	}{
+		{
+			name:        "captures with different names",
+			links:       map[string]Link{"/a/{x}": link("https://x.example", false), "/a/{y}": link("https://y.example", false)},
+			path:        "/a/1",
+			expectedURL: "https://x.example",
+		},
+		{
+			name:        "wildcard and capture",
+			links:       map[string]Link{"/a/*": link("https://wildcard.example", false), "/a/{x}": link("https://capture.example", false)},
+			path:        "/a/1",
+			expectedURL: "https://wildcard.example",
+		},
+		{
+			name:        "rest captures",
+			links:       map[string]Link{"/a/{p...}": link("https://p.example", false), "/a/{q...}": link("https://q.example", false)},
+			path:        "/a/1/2",
+			expectedURL: "https://p.example",
+		},
+		{
+			name:        "prefix and rest capture",
+			links:       map[string]Link{"/a": link("https://prefix.example", true), "/a/{p...}": link("https://rest.example", false)},
+			path:        "/a/1/2",
+			expectedURL: "https://prefix.example/1/2",
+		},
+		{
+			name:        "prefix with and without a trailing slash",
+			links:       map[string]Link{"/a/": link("https://slash.example", true), "/a": link("https://no-slash.example", true)},
+			path:        "/a/1",
+			expectedURL: "https://no-slash.example/1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The rules are added in order of their paths, so the same one is rejected however the map is ordered
+			for i := 0; i < 10; i++ {
+				m, errs := buildMatcher(tc.links)
+				assert.Len(t, errs, 1, "expected the clash to be reported")
+
+				_, target, ok, err := m.match(context.Background(), tc.path)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, tc.expectedURL, target)
+			}
+		})
+	}
+}
+
+func Test_matcherNoClash(t *testing.T) {
+	_, errs := buildMatcher(map[string]Link{
+		"/a/{x}":      {URL: "https://x.example"},
+		"/a/{x}/b":    {URL: "https://b.example"},
+		"/a/special":  {URL: "https://special.example"},
+		"/a/{p...}":   {URL: "https://rest.example"},
+		"/docs":       {URL: "https://docs.example", Prefix: true},
+		"/docs/{x}/y": {URL: "https://y.example"},
+	})
+	assert.Empty(t, errs, "expected rules which are more specific than each other not to clash")
+}
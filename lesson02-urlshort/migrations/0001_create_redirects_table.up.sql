@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS redirects (
+    id SERIAL PRIMARY KEY,
+    urlpath VARCHAR(100) NOT NULL,
+    urltarget VARCHAR(100) NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id BIGSERIAL PRIMARY KEY,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    urlpath TEXT NOT NULL,
+    referrer TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    client_network VARCHAR(64) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS clicks_urlpath_clicked_at ON clicks (urlpath, clicked_at);
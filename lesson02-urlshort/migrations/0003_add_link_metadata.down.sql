@@ -0,0 +1,4 @@
+ALTER TABLE redirects
+    DROP COLUMN status,
+    DROP COLUMN expires_at,
+    DROP COLUMN enabled;
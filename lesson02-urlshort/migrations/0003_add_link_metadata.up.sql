@@ -0,0 +1,4 @@
+ALTER TABLE redirects
+    ADD COLUMN IF NOT EXISTS status SMALLINT NOT NULL DEFAULT 301 CHECK (status IN (301, 302, 307, 308)),
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
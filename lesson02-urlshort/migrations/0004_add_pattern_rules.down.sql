@@ -0,0 +1,3 @@
+ALTER TABLE redirects
+    DROP COLUMN prefix,
+    DROP COLUMN pass_query;
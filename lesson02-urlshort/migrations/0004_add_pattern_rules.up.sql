@@ -0,0 +1,3 @@
+ALTER TABLE redirects
+    ADD COLUMN IF NOT EXISTS prefix BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS pass_query BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- urltarget is left as TEXT, as targets which are too long for the old VARCHAR(100) column would fail the migration
+
+DROP INDEX redirects_urlpath;
@@ -0,0 +1,6 @@
+-- Each request looks up its path, so index it. This fails if a path is already in the table more than once, in which
+-- case the duplicates need to be removed by hand first
+CREATE UNIQUE INDEX redirects_urlpath ON redirects (urlpath);
+
+-- Long URLs are the whole point of a URL shortener
+ALTER TABLE redirects ALTER COLUMN urltarget TYPE TEXT;
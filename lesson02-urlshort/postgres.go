@@ -0,0 +1,205 @@
+package urlshort
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// redirectColumns are the columns of the redirects table, in the order scanRedirect reads them
+const redirectColumns = "id, urlpath, urltarget, status, expires_at, enabled, prefix, pass_query"
+
+// PostgresStore keeps the redirects in the Postgres redirects table.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore returns a PostgresStore which uses db.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) All(ctx context.Context) (redirects, error) {
+	return queryRedirects(ctx, s.db, "SELECT "+redirectColumns+" FROM redirects")
+}
+
+// Rules returns the pattern and prefix rules, which can't be looked up by their path. Every other redirect is looked
+// up with Get, using the index on urlpath.
+func (s *PostgresStore) Rules(ctx context.Context) (redirects, error) {
+	return queryRedirects(ctx, s.db, "SELECT "+redirectColumns+" FROM redirects WHERE prefix OR urlpath LIKE '%{%' OR urlpath LIKE '%*%'")
+}
+
+func (s *PostgresStore) List(ctx context.Context, offset, limit int) (redirects, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM redirects").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("counting redirects: %v", err)
+	}
+
+	records, err := queryRedirects(ctx, s.db, "SELECT "+redirectColumns+" FROM redirects ORDER BY urlpath LIMIT $1 OFFSET $2", limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, nil
+}
+
+func (s *PostgresStore) Get(ctx context.Context, path string) (redirect, error) {
+	return s.queryOne(ctx, "SELECT "+redirectColumns+" FROM redirects WHERE urlpath = $1", path)
+}
+
+func (s *PostgresStore) FindByURL(ctx context.Context, url string) (redirect, error) {
+	return s.queryOne(ctx,
+		`SELECT `+redirectColumns+` FROM redirects
+		 WHERE urltarget = $1 AND enabled AND (expires_at IS NULL OR expires_at > now())
+		 ORDER BY id LIMIT 1`, url)
+}
+
+func (s *PostgresStore) Create(ctx context.Context, r redirect) (redirect, error) {
+	return insertRedirect(ctx, s.db, r)
+}
+
+func (s *PostgresStore) Update(ctx context.Context, r redirect) (redirect, error) {
+	return updateRedirect(ctx, s.db, r)
+}
+
+// WriteBatch creates and updates the redirects in a single transaction.
+func (s *PostgresStore) WriteBatch(ctx context.Context, creates, updates redirects) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %v", err)
+	}
+	// Rolling back after the commit does nothing
+	defer func() { _ = tx.Rollback() }()
+
+	for _, r := range creates {
+		if _, err = insertRedirect(ctx, tx, r); err != nil {
+			return fmt.Errorf("creating %s: %w", r.Path, err)
+		}
+	}
+	for _, r := range updates {
+		if _, err = updateRedirect(ctx, tx, r); err != nil {
+			return fmt.Errorf("updating %s: %w", r.Path, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, path string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM redirects WHERE urlpath = $1", path)
+	if err != nil {
+		return fmt.Errorf("deleting redirect: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting redirect: %v", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// queryOne returns the redirect selected by query, or ErrNotFound.
+func (s *PostgresStore) queryOne(ctx context.Context, query string, args ...any) (redirect, error) {
+	r, err := scanRedirect(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return redirect{}, ErrNotFound
+	}
+	if err != nil {
+		return redirect{}, fmt.Errorf("querying database: %v", err)
+	}
+
+	return r, nil
+}
+
+// queryRedirects returns the redirects selected by query.
+func queryRedirects(ctx context.Context, db *sql.DB, query string, args ...any) (redirects, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %v", err)
+	}
+	defer rows.Close()
+
+	records := make(redirects, 0)
+	for rows.Next() {
+		r, err := scanRedirect(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reading database records: %v", err)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading database records: %v", err)
+	}
+
+	return records, nil
+}
+
+// rowQuerier is a *sql.DB or *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertRedirect adds r to the redirects table, or returns ErrExists if its path is already in use.
+func insertRedirect(ctx context.Context, q rowQuerier, r redirect) (redirect, error) {
+	l := r.link()
+
+	err := q.QueryRowContext(ctx,
+		`INSERT INTO redirects (urlpath, urltarget, status, expires_at, enabled, prefix, pass_query)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (urlpath) DO NOTHING
+		 RETURNING id`, r.Path, r.URL, l.Status, r.ExpiresAt, l.Enabled, r.Prefix, r.PassQuery).Scan(&r.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return redirect{}, ErrExists
+	}
+	if err != nil {
+		return redirect{}, fmt.Errorf("inserting redirect: %v", err)
+	}
+
+	return r, nil
+}
+
+// updateRedirect replaces the row with the same path as r, or returns ErrNotFound.
+func updateRedirect(ctx context.Context, q rowQuerier, r redirect) (redirect, error) {
+	l := r.link()
+
+	err := q.QueryRowContext(ctx,
+		`UPDATE redirects SET urltarget = $2, status = $3, expires_at = $4, enabled = $5, prefix = $6, pass_query = $7
+		 WHERE urlpath = $1 RETURNING id`, r.Path, r.URL, l.Status, r.ExpiresAt, l.Enabled, r.Prefix, r.PassQuery).Scan(&r.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return redirect{}, ErrNotFound
+	}
+	if err != nil {
+		return redirect{}, fmt.Errorf("updating redirect: %v", err)
+	}
+
+	return r, nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRedirect reads a redirect from the redirectColumns of a row.
+func scanRedirect(row rowScanner) (redirect, error) {
+	var r redirect
+	var expiresAt sql.NullTime
+	var enabled bool
+	if err := row.Scan(&r.Id, &r.Path, &r.URL, &r.Status, &expiresAt, &enabled, &r.Prefix, &r.PassQuery); err != nil {
+		return redirect{}, err
+	}
+	if expiresAt.Valid {
+		t := expiresAt.Time.UTC()
+		r.ExpiresAt = &t
+	}
+	r.Enabled = &enabled
+
+	return r, nil
+}
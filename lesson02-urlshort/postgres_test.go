@@ -0,0 +1,168 @@
+package urlshort
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// openTestDB connects to the Postgres database in URLSHORT_TEST_DATABASE_URL and migrates it, skipping the test
+// when it isn't set. The tables are emptied so each test starts afresh.
+func openTestDB(t *testing.T) *sql.DB {
+	url := os.Getenv("URLSHORT_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("URLSHORT_TEST_DATABASE_URL is not set, e.g. make run_db test_integration")
+	}
+
+	db, err := OpenDB(DBConfig{URL: url, MaxOpenConns: 5, MaxIdleConns: 2})
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	// Migrating twice checks that an up to date database is left alone
+	assert.NoError(t, Migrate(db))
+	assert.NoError(t, Migrate(db))
+
+	_, err = db.Exec("TRUNCATE redirects, clicks")
+	assert.NoError(t, err)
+
+	return db
+}
+
+func Test_PostgresStore(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	store := NewPostgresStore(db)
+
+	created, err := store.Create(ctx, redirect{Path: "/a", URL: "https://a.example", Status: http.StatusFound})
+	assert.NoError(t, err)
+	assert.NotZero(t, created.Id)
+
+	_, err = store.Create(ctx, redirect{Path: "/a", URL: "https://other.example"})
+	assert.ErrorIs(t, err, ErrExists)
+
+	_, err = store.Create(ctx, redirect{Path: "/gh/{repo}", URL: "https://github.com/org/{repo}"})
+	assert.NoError(t, err)
+
+	got, err := store.Get(ctx, "/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://a.example", got.URL)
+	assert.Equal(t, http.StatusFound, got.Status)
+
+	found, err := store.FindByURL(ctx, "https://a.example")
+	assert.NoError(t, err)
+	assert.Equal(t, "/a", found.Path)
+
+	rules, err := store.Rules(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rules))
+	assert.Equal(t, "/gh/{repo}", rules[0].Path)
+
+	expires := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	disabled := false
+	updated, err := store.Update(ctx, redirect{Path: "/a", URL: "https://b.example", ExpiresAt: &expires, Enabled: &disabled})
+	assert.NoError(t, err)
+	assert.Equal(t, created.Id, updated.Id)
+
+	got, err = store.Get(ctx, "/a")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, got.Status, "expected the status to default to 301")
+	assert.True(t, expires.Equal(*got.ExpiresAt))
+	assert.False(t, *got.Enabled)
+
+	_, err = store.FindByURL(ctx, "https://b.example")
+	assert.ErrorIs(t, err, ErrNotFound, "expected disabled links not to be found")
+
+	list, total, err := store.List(ctx, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, "/gh/{repo}", list[0].Path)
+
+	assert.NoError(t, store.Delete(ctx, "/a"))
+	assert.ErrorIs(t, store.Delete(ctx, "/a"), ErrNotFound)
+	_, err = store.Get(ctx, "/a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// A batch is written in a transaction, so nothing is written when any redirect fails
+	err = store.WriteBatch(ctx, redirects{{Path: "/c", URL: "https://c.example"}, {Path: "/gh/{repo}", URL: "https://other.example"}}, nil)
+	assert.ErrorIs(t, err, ErrExists)
+	_, err = store.Get(ctx, "/c")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.WriteBatch(ctx, redirects{{Path: "/c", URL: "https://c.example"}}, redirects{{Path: "/gh/{repo}", URL: "https://github.com/other/{repo}"}}))
+	got, err = store.Get(ctx, "/gh/{repo}")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/other/{repo}", got.URL)
+}
+
+func Test_DBHandler(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	store := NewPostgresStore(db)
+
+	_, err := store.Create(ctx, redirect{Path: "/gh/{repo}", URL: "https://github.com/org/{repo}"})
+	assert.NoError(t, err)
+
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler, err := DBHandler(db, fallback)
+	assert.NoError(t, err)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusTeapot, serve("/new").Code)
+
+	// Exact paths are looked up on each request, so a new redirect is served without reloading
+	_, err = store.Create(ctx, redirect{Path: "/new", URL: "https://new.example"})
+	assert.NoError(t, err)
+	w := serve("/new")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://new.example", w.Header().Get("Location"))
+
+	w = serve("/gh/urlshort")
+	assert.Equal(t, "https://github.com/org/urlshort", w.Header().Get("Location"))
+}
+
+func Test_PostgresClickStore(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	store := NewPostgresClickStore(db)
+
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	assert.NoError(t, store.SaveClicks(ctx, []Click{
+		{Time: start.Add(time.Minute), Path: "/a", Referrer: "https://news.example", ClientNetwork: "203.0.113.0/24"},
+		{Time: start.Add(2 * time.Minute), Path: "/a"},
+		{Time: start.Add(time.Hour), Path: "/a"},
+		{Time: start, Path: "/b"},
+	}))
+
+	stats, err := store.Stats(ctx, "/a", "hour", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Total)
+	assert.Equal(t, []BucketCount{{Start: start, Count: 2}, {Start: start.Add(time.Hour), Count: 1}}, stats.Buckets)
+
+	stats, err = store.Stats(ctx, "/a", "day", start.Add(30*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+
+	// A prefix rule matches paths of any length, which shouldn't stop the rest of the batch being saved
+	long := "/docs/" + strings.Repeat("a", 200)
+	assert.NoError(t, store.SaveClicks(ctx, []Click{{Time: start, Path: long}, {Time: start, Path: "/c"}}))
+	stats, err = store.Stats(ctx, long, "day", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+}
@@ -0,0 +1,110 @@
+package urlshort
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadingHandler is a http.Handler which serves redirects from a mapping that can be swapped whilst the server
+// is running. The swap is atomic, so every request is served by either the old or the new mapping in full.
+type ReloadingHandler struct {
+	fallback http.Handler
+	current  atomic.Value // http.Handler
+}
+
+// NewReloadingHandler returns a ReloadingHandler which starts with links, calling fallback for any other paths.
+func NewReloadingHandler(links map[string]Link, fallback http.Handler) *ReloadingHandler {
+	h := &ReloadingHandler{fallback: fallback}
+	h.Swap(links)
+
+	return h
+}
+
+// ServeHTTP redirects the request using the current mapping.
+func (h *ReloadingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.current.Load().(http.Handler).ServeHTTP(w, r)
+}
+
+// Swap replaces the current mapping.
+func (h *ReloadingHandler) Swap(links map[string]Link) {
+	h.current.Store(http.Handler(LinkHandler(links, h.fallback)))
+}
+
+// WatchFile loads the redirects from a YAML or JSON config file (chosen by its extension) and then reloads it every
+// interval until ctx is cancelled, see Watch.
+func (h *ReloadingHandler) WatchFile(ctx context.Context, path string, interval time.Duration) error {
+	return h.Watch(ctx, NewFileStore(path), interval)
+}
+
+// Watch loads the redirects from store and then reloads them every interval until ctx is cancelled, swapping in the
+// new mapping whenever it changes. Sources which can look up a path quickly, such as the database, are instead looked
+// up on each request, and only their pattern and prefix rules are reloaded. An error is only returned if the first
+// load fails. A later reload which fails keeps the current mapping and logs the error, so a bad edit doesn't take
+// every redirect down.
+func (h *ReloadingHandler) Watch(ctx context.Context, store Store, interval time.Duration) error {
+	chain, ok := store.(*Chain)
+	if !ok {
+		chain = NewChain(Source{Name: "store", Store: store})
+	}
+
+	snap, err := chain.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	h.swapSnapshot(snap)
+
+	go h.poll(ctx, interval, func() {
+		latest, err := chain.snapshot(ctx)
+		if err != nil {
+			slog.Error(fmt.Sprintf("unable to reload redirects, keeping the current redirects: %v", err))
+			return
+		}
+
+		if latest.equal(snap) {
+			return
+		}
+		snap = latest
+		h.swapSnapshot(snap)
+		slog.Info("Reloaded the redirects")
+	})
+
+	return nil
+}
+
+// swapSnapshot replaces the current mapping with the redirects in snap.
+func (h *ReloadingHandler) swapSnapshot(snap snapshot) {
+	h.current.Store(http.Handler(matcherHandler(newLayeredMatcher(snap.layers, snap.rules), h.fallback)))
+}
+
+// poll calls reload every interval until ctx is cancelled.
+func (h *ReloadingHandler) poll(ctx context.Context, interval time.Duration, reload func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reload()
+		}
+	}
+}
+
+// mapsEqual reports whether two mappings contain the same redirects.
+func mapsEqual(a, b map[string]Link) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, link := range a {
+		if other, ok := b[path]; !ok || !link.equal(other) {
+			return false
+		}
+	}
+
+	return true
+}
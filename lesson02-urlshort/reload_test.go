@@ -0,0 +1,54 @@
+package urlshort
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// location returns where handler redirects path to, or an empty string when it doesn't redirect.
+func location(handler http.Handler, path string) string {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return w.Header().Get("Location")
+}
+
+func Test_WatchFile(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	// Replace the file as editors do, so that a poll never sees it part way through being written
+	write := func(content string, modified time.Time) {
+		assert.NoError(t, os.WriteFile(path+".tmp", []byte(content), 0o644))
+		assert.NoError(t, os.Chtimes(path+".tmp", modified, modified))
+		assert.NoError(t, os.Rename(path+".tmp", path))
+	}
+	start := time.Now().Add(-time.Hour)
+	write("- path: /old\n  url: https://old.example\n", start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := NewReloadingHandler(nil, http.NotFoundHandler())
+	assert.NoError(t, h.WatchFile(ctx, path, interval))
+	assert.Equal(t, "https://old.example", location(h, "/old"))
+
+	write("- path: /new\n  url: https://new.example\n", start.Add(time.Minute))
+	assert.Eventually(t, func() bool { return location(h, "/new") == "https://new.example" }, time.Second, interval, "expected the rewritten file to be served")
+
+	// The invalid file has the same size and modification time as the valid edit which follows it, so the edit can
+	// only be picked up if the failed reload is tried again
+	valid := "- path: /fixed\n  url: https://fixed.example\n"
+	write("{"+strings.Repeat("x", len(valid)-1), start.Add(2*time.Minute))
+	time.Sleep(5 * interval)
+	assert.Equal(t, "https://new.example", location(h, "/new"), "expected the current redirects to be kept when the file is invalid")
+
+	write(valid, start.Add(2*time.Minute))
+	assert.Eventually(t, func() bool { return location(h, "/fixed") == "https://fixed.example" }, time.Second, interval, "expected a failed reload to be tried again")
+}
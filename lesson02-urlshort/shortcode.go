@@ -0,0 +1,90 @@
+package urlshort
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// DefaultCodeAlphabet is the characters used in generated short codes unless another alphabet is configured
+	DefaultCodeAlphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// DefaultCodeLength is the length of generated short codes unless another length is configured
+	DefaultCodeLength = 6
+
+	// codeAttempts is how many codes are tried before giving up, as each one could already be in use
+	codeAttempts = 5
+)
+
+// ErrNoFreeCode is returned when every generated code was already in use, which suggests the codes are too short.
+var ErrNoFreeCode = errors.New("unable to generate an unused short code, try a longer code length")
+
+// CodeGenerator generates random short codes, such as 'aZ3k9Q'. Codes are checked against the store when they are
+// used, and a new one is generated when there is a collision.
+type CodeGenerator struct {
+	alphabet []rune
+	length   int
+}
+
+// NewCodeGenerator returns a CodeGenerator for codes of length characters from alphabet.
+func NewCodeGenerator(alphabet string, length int) (*CodeGenerator, error) {
+	if length < 1 {
+		return nil, fmt.Errorf("code length must be at least 1, not %d", length)
+	}
+
+	runes := []rune(alphabet)
+	if len(runes) < 2 {
+		return nil, fmt.Errorf("code alphabet must have at least 2 characters")
+	}
+	seen := make(map[rune]bool)
+	for _, c := range runes {
+		if seen[c] {
+			return nil, fmt.Errorf("code alphabet has '%c' more than once", c)
+		}
+		if !strings.ContainsRune(DefaultCodeAlphabet+"-_", c) {
+			return nil, fmt.Errorf("code alphabet can only contain letters, numbers, '-' and '_', not '%c'", c)
+		}
+		seen[c] = true
+	}
+
+	return &CodeGenerator{alphabet: runes, length: length}, nil
+}
+
+// Generate returns a new random code.
+func (g *CodeGenerator) Generate() (string, error) {
+	code := make([]rune, g.length)
+	size := big.NewInt(int64(len(g.alphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", fmt.Errorf("generating short code: %v", err)
+		}
+		code[i] = g.alphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// CreateWithCode adds link to store under a newly generated code as its path, generating another code if the first
+// is already in use.
+func (g *CodeGenerator) CreateWithCode(ctx context.Context, store Store, link redirect) (redirect, error) {
+	for i := 0; i < codeAttempts; i++ {
+		code, err := g.Generate()
+		if err != nil {
+			return redirect{}, err
+		}
+
+		link.Path = "/" + code
+		created, err := store.Create(ctx, link)
+		if errors.Is(err, ErrExists) {
+			continue
+		}
+
+		return created, err
+	}
+
+	return redirect{}, ErrNoFreeCode
+}
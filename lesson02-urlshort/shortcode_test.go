@@ -0,0 +1,93 @@
+package urlshort
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collidingStore returns ErrExists for the first collisions calls to Create, and counts every call.
+type collidingStore struct {
+	Store
+	collisions int
+	creates    int
+}
+
+func (s *collidingStore) Create(ctx context.Context, r redirect) (redirect, error) {
+	s.creates++
+	if s.creates <= s.collisions {
+		return redirect{}, ErrExists
+	}
+
+	return s.Store.Create(ctx, r)
+}
+
+// newTestFileStore returns a FileStore backed by a temporary YAML file containing red.
+func newTestFileStore(t *testing.T, red redirects) *FileStore {
+	t.Helper()
+
+	store := NewFileStore(filepath.Join(t.TempDir(), "config.yaml"))
+	assert.NoError(t, store.save(red))
+
+	return store
+}
+
+func Test_NewCodeGenerator(t *testing.T) {
+	tt := []struct {
+		name     string
+		alphabet string
+		length   int
+	}{
+		{name: "zero length", alphabet: DefaultCodeAlphabet, length: 0},
+		{name: "single character alphabet", alphabet: "a", length: 6},
+		{name: "repeated character", alphabet: "abca", length: 6},
+		{name: "character not allowed in a path", alphabet: "ab/", length: 6},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewCodeGenerator(tc.alphabet, tc.length)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_CodeGeneratorGenerate(t *testing.T) {
+	codes, err := NewCodeGenerator("xyz-_", 8)
+	assert.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		code, err := codes.Generate()
+		assert.NoError(t, err)
+		assert.Len(t, code, 8)
+		assert.Empty(t, strings.Trim(code, "xyz-_"), "expected only characters from the alphabet in %s", code)
+	}
+}
+
+func Test_CreateWithCode(t *testing.T) {
+	// One character codes from a two letter alphabet, so that collisions are easy to force
+	codes, err := NewCodeGenerator("ab", 1)
+	assert.NoError(t, err)
+
+	t.Run("retries a collision", func(t *testing.T) {
+		store := &collidingStore{Store: newTestFileStore(t, redirects{}), collisions: 2}
+
+		created, err := codes.CreateWithCode(context.Background(), store, redirect{URL: "https://example.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, store.creates, "expected a new code to be tried after each collision")
+		assert.Contains(t, []string{"/a", "/b"}, created.Path)
+		assert.Equal(t, "https://example.com", created.URL)
+	})
+
+	t.Run("gives up when every code is in use", func(t *testing.T) {
+		store := &collidingStore{Store: newTestFileStore(t, redirects{{Path: "/a", URL: "https://a.example"}, {Path: "/b", URL: "https://b.example"}})}
+
+		_, err := codes.CreateWithCode(context.Background(), store, redirect{URL: "https://example.com"})
+		assert.True(t, errors.Is(err, ErrNoFreeCode), "expected ErrNoFreeCode, got %v", err)
+		assert.Equal(t, codeAttempts, store.creates)
+	})
+}
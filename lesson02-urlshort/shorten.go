@@ -0,0 +1,96 @@
+package urlshort
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// shortenPath is where the ShortenHandler is served, so short links can't use it
+const shortenPath = "/shorten"
+
+// shortenRequest is the body of a request to shorten a URL.
+type shortenRequest struct {
+	URL string `json:"url"`
+}
+
+// shortenResponse is the short link for a shortened URL.
+type shortenResponse struct {
+	ShortURL string `json:"short_url"`
+	Path     string `json:"path"`
+	URL      string `json:"url"`
+}
+
+// ShortenHandler serves 'POST /shorten', which takes a long URL as {"url": "https://..."} and returns its short URL.
+// Shortening a URL which already has a short link returns the existing link rather than creating another one.
+//
+// As with the admin API, every request must send the API token as an 'Authorization: Bearer <token>' header.
+type ShortenHandler struct {
+	store   Store
+	codes   *CodeGenerator
+	token   string
+	baseURL string
+
+	// mu stops two requests for the same URL both creating a new link
+	mu sync.Mutex
+}
+
+// NewShortenHandler returns a ShortenHandler which adds links to store. The short URLs start with baseURL
+// (e.g. https://sho.rt), or with the scheme and host of the request when it is empty.
+func NewShortenHandler(store Store, codes *CodeGenerator, token, baseURL string) *ShortenHandler {
+	return &ShortenHandler{store: store, codes: codes, token: token, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (h *ShortenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorised(r, h.token) {
+		unauthorised(w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, "POST")
+		return
+	}
+
+	var req shortenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if err := validateURL(req.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := http.StatusOK
+	link, err := h.store.FindByURL(r.Context(), req.URL)
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusCreated
+		link, err = h.codes.CreateWithCode(r.Context(), h.store, redirect{URL: req.URL})
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, status, shortenResponse{ShortURL: h.base(r) + link.Path, Path: link.Path, URL: link.URL})
+}
+
+// base returns the start of the short URLs.
+func (h *ShortenHandler) base(r *http.Request) string {
+	if h.baseURL != "" {
+		return h.baseURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
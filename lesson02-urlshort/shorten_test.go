@@ -0,0 +1,60 @@
+package urlshort
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ShortenHandler(t *testing.T) {
+	codes, err := NewCodeGenerator("ab", 1)
+	assert.NoError(t, err)
+	store := newTestFileStore(t, redirects{{Path: "/existing", URL: "https://existing.example"}})
+	handler := NewShortenHandler(store, codes, testToken, "https://sho.rt/")
+
+	shorten := func(body string) (int, shortenResponse) {
+		r := httptest.NewRequest(http.MethodPost, shortenPath, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+testToken)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		var resp shortenResponse
+		if w.Code < http.StatusBadRequest {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+
+	status, resp := shorten(`{"url": "https://existing.example"}`)
+	assert.Equal(t, http.StatusOK, status, "expected the existing link to be returned")
+	assert.Equal(t, shortenResponse{ShortURL: "https://sho.rt/existing", Path: "/existing", URL: "https://existing.example"}, resp)
+
+	status, created := shorten(`{"url": "https://new.example"}`)
+	assert.Equal(t, http.StatusCreated, status, "expected a new link to be created")
+	assert.Equal(t, "https://sho.rt"+created.Path, created.ShortURL)
+
+	status, resp = shorten(`{"url": "https://new.example"}`)
+	assert.Equal(t, http.StatusOK, status, "expected the link created for the URL to be reused")
+	assert.Equal(t, created, resp)
+
+	// Use up both of the one letter codes with other URLs
+	assert.NoError(t, store.Delete(context.Background(), created.Path))
+	for _, path := range []string{"/a", "/b"} {
+		_, err = store.Create(context.Background(), redirect{Path: path, URL: "https://other.example" + path})
+		assert.NoError(t, err)
+	}
+	status, _ = shorten(`{"url": "https://one-too-many.example"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, status, "expected an error once every code is in use")
+
+	status, _ = shorten(`{"url": "not a url"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, shortenPath, strings.NewReader(`{"url": "https://new.example"}`)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
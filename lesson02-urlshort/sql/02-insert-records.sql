@@ -1,4 +0,0 @@
-INSERT INTO redirects (urlpath, urltarget)
-VALUES('/db1', 'https://www.path-from-db-1.com'),
-      ('/db2', 'https://www.path-from-db-2.com'),
-      ('/db3', 'https://www.path-from-db-3.com');
\ No newline at end of file
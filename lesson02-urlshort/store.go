@@ -0,0 +1,296 @@
+package urlshort
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNotFound is returned by a Store when there is no redirect for a path
+	ErrNotFound = errors.New("redirect not found")
+	// ErrExists is returned by a Store when creating a redirect for a path which is already in use
+	ErrExists = errors.New("a redirect already exists for this path")
+	// ErrReadOnly is returned by a Store whose redirects can't be changed
+	ErrReadOnly = errors.New("this redirect can't be changed")
+)
+
+// Store is somewhere the redirects are kept, which can be changed whilst the server is running.
+type Store interface {
+	// All returns every redirect
+	All(ctx context.Context) (redirects, error)
+	// List returns up to limit redirects ordered by path, starting at offset, along with the total number of redirects
+	List(ctx context.Context, offset, limit int) (redirects, int, error)
+	// Get returns the redirect for path, or ErrNotFound
+	Get(ctx context.Context, path string) (redirect, error)
+	// FindByURL returns the first enabled and unexpired redirect which targets url, or ErrNotFound
+	FindByURL(ctx context.Context, url string) (redirect, error)
+	// Create adds a new redirect, or returns ErrExists if its path is already in use
+	Create(ctx context.Context, r redirect) (redirect, error)
+	// Update replaces the redirect with the same path, or returns ErrNotFound
+	Update(ctx context.Context, r redirect) (redirect, error)
+	// Delete removes the redirect for path, or returns ErrNotFound
+	Delete(ctx context.Context, path string) error
+}
+
+// batchStore is a Store which can write many redirects at once, so that either every one of them is written or none
+// of them are.
+type batchStore interface {
+	Store
+	// WriteBatch creates and updates the redirects together, failing as Create and Update do for any one of them
+	WriteBatch(ctx context.Context, creates, updates redirects) error
+}
+
+// readOnlyStore is a Store which may not allow its redirects to be changed, so that a Chain knows which of its sources
+// new redirects can be created in.
+type readOnlyStore interface {
+	Store
+	// ReadOnly reports whether Create, Update and Delete always return ErrReadOnly
+	ReadOnly() bool
+}
+
+// writeBatch creates and updates the redirects in store, all at once when it is a batchStore, or otherwise one at a
+// time, stopping at the first which fails.
+func writeBatch(ctx context.Context, store Store, creates, updates redirects) error {
+	if b, ok := store.(batchStore); ok {
+		return b.WriteBatch(ctx, creates, updates)
+	}
+
+	for _, r := range creates {
+		if _, err := store.Create(ctx, r); err != nil {
+			return fmt.Errorf("creating %s: %w", r.Path, err)
+		}
+	}
+	for _, r := range updates {
+		if _, err := store.Update(ctx, r); err != nil {
+			return fmt.Errorf("updating %s: %w", r.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// FileStore keeps the redirects in a YAML or JSON config file, chosen by its extension. The whole file is read for
+// every operation and rewritten for every change, so it suits the small sets of redirects kept in config files.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore returns a FileStore which uses the config file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) All(_ context.Context) (redirects, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+func (s *FileStore) List(_ context.Context, offset, limit int) (redirects, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	red, err := s.load()
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(red, func(i, j int) bool { return red[i].Path < red[j].Path })
+
+	return page(red, offset, limit), len(red), nil
+}
+
+func (s *FileStore) Get(_ context.Context, path string) (redirect, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	red, err := s.load()
+	if err != nil {
+		return redirect{}, err
+	}
+	i := red.index(path)
+	if i < 0 {
+		return redirect{}, ErrNotFound
+	}
+
+	return red[i], nil
+}
+
+func (s *FileStore) FindByURL(_ context.Context, url string) (redirect, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	red, err := s.load()
+	if err != nil {
+		return redirect{}, err
+	}
+	now := time.Now()
+	for _, r := range red {
+		if r.URL == url && r.active(now) {
+			return r, nil
+		}
+	}
+
+	return redirect{}, ErrNotFound
+}
+
+func (s *FileStore) Create(_ context.Context, r redirect) (redirect, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	red, err := s.load()
+	if err != nil {
+		return redirect{}, err
+	}
+	if red.index(r.Path) >= 0 {
+		return redirect{}, ErrExists
+	}
+
+	return r, s.save(append(red, r))
+}
+
+func (s *FileStore) Update(_ context.Context, r redirect) (redirect, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	red, err := s.load()
+	if err != nil {
+		return redirect{}, err
+	}
+	i := red.index(r.Path)
+	if i < 0 {
+		return redirect{}, ErrNotFound
+	}
+	r.Id = red[i].Id
+	red[i] = r
+
+	return r, s.save(red)
+}
+
+func (s *FileStore) Delete(_ context.Context, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	red, err := s.load()
+	if err != nil {
+		return err
+	}
+	i := red.index(path)
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	return s.save(append(red[:i], red[i+1:]...))
+}
+
+// WriteBatch creates and updates the redirects with a single rewrite of the config file.
+func (s *FileStore) WriteBatch(_ context.Context, creates, updates redirects) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	red, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, r := range creates {
+		if red.index(r.Path) >= 0 {
+			return fmt.Errorf("creating %s: %w", r.Path, ErrExists)
+		}
+		red = append(red, r)
+	}
+	for _, r := range updates {
+		i := red.index(r.Path)
+		if i < 0 {
+			return fmt.Errorf("updating %s: %w", r.Path, ErrNotFound)
+		}
+		r.Id = red[i].Id
+		red[i] = r
+	}
+
+	return s.save(red)
+}
+
+// isJSON reports whether the config file is JSON rather than YAML.
+func (s *FileStore) isJSON() bool {
+	return strings.EqualFold(filepath.Ext(s.path), ".json")
+}
+
+// load reads every redirect from the config file.
+func (s *FileStore) load() (redirects, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading file %s: %v", s.path, err)
+	}
+
+	var red redirects
+	if s.isJSON() {
+		red, err = parseJSON(b)
+	} else {
+		red, err = parseYAML(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing file %s: %v", s.path, err)
+	}
+
+	return red, nil
+}
+
+// save replaces the config file with red.
+func (s *FileStore) save(red redirects) error {
+	var b []byte
+	var err error
+	if s.isJSON() {
+		b, err = json.MarshalIndent(red, "", "  ")
+	} else {
+		b, err = yaml.Marshal(red)
+	}
+	if err != nil {
+		return fmt.Errorf("marshalling: %v", err)
+	}
+
+	// Write to a temporary file and rename it over the config file, so a reload never sees a half written file
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("writing file %s: %v", tmp, err)
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replacing file %s: %v", s.path, err)
+	}
+
+	return nil
+}
+
+// index returns the position of the redirect for path, or -1 if there isn't one.
+func (red redirects) index(path string) int {
+	for i, r := range red {
+		if r.Path == path {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// page returns up to limit redirects starting at offset.
+func page(red redirects, offset, limit int) redirects {
+	if offset >= len(red) {
+		return redirects{}
+	}
+	end := offset + limit
+	if end > len(red) {
+		end = len(red)
+	}
+
+	return red[offset:end]
+}
@@ -0,0 +1,331 @@
+package urlshort
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The formats which redirects can be exported to and imported from
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// csvColumns are the columns of an exported CSV file. Only path and url are needed when importing.
+var csvColumns = []string{"path", "url", "status", "expires_at", "enabled", "prefix", "pass_query"}
+
+// FormatFor returns the format of a file from its extension, or "" when it isn't one of the formats.
+func FormatFor(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	}
+
+	return ""
+}
+
+// MergeStrategy is what an import does with a path which is already in the store with a different redirect.
+type MergeStrategy string
+
+const (
+	// MergeSkip keeps the redirect which is already in the store
+	MergeSkip MergeStrategy = "skip"
+	// MergeOverwrite replaces the redirect in the store with the imported one
+	MergeOverwrite MergeStrategy = "overwrite"
+	// MergeFail stops the import before anything is written
+	MergeFail MergeStrategy = "fail"
+)
+
+// ParseMergeStrategy returns the MergeStrategy called s.
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch m := MergeStrategy(s); m {
+	case MergeSkip, MergeOverwrite, MergeFail:
+		return m, nil
+	}
+
+	return "", fmt.Errorf("unknown merge strategy '%s', expected one of: skip, overwrite, fail", s)
+}
+
+// ImportReport lists the paths which an import created, updated or left alone.
+type ImportReport struct {
+	Created []string
+	// Updated are the conflicting paths which were overwritten
+	Updated []string
+	// Unchanged are the paths which were already in the store with the same redirect
+	Unchanged []string
+	// Skipped are the conflicting paths which were left as they are
+	Skipped []string
+	// Duplicates are the paths listed more than once in the import, of which only the first is used
+	Duplicates []string
+	// Conflicts are the paths which were already in the store with a different redirect
+	Conflicts []string
+}
+
+// ExportRedirects writes every redirect in store to w in format, ordered by path, returning how many were written.
+// The ids are left out, as each store assigns its own.
+func ExportRedirects(ctx context.Context, store Store, w io.Writer, format string) (int, error) {
+	red, err := store.All(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	out := make(redirects, len(red))
+	copy(out, red)
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	for i := range out {
+		out[i].Id = 0
+	}
+
+	if err = encodeRedirects(w, format, out); err != nil {
+		return 0, err
+	}
+
+	return len(out), nil
+}
+
+// ImportRedirects reads redirects in format from r and adds them to store. A path which is already in the store with
+// a different redirect is a conflict, which is handled by merge. Every redirect is checked before anything is
+// written, so nothing is imported when one is invalid or merge is MergeFail and there are conflicts. The redirects
+// are then written together, in a single transaction or rewrite of the file, so a write which fails imports nothing
+// either. Only a Chain of several sources, or a Store which can't write a batch, can be left with a partial import.
+// With dryRun the report says what would be imported, without changing the store.
+func ImportRedirects(ctx context.Context, store Store, r io.Reader, format string, merge MergeStrategy, dryRun bool) (ImportReport, error) {
+	var report ImportReport
+	red, err := decodeRedirects(r, format)
+	if err != nil {
+		return report, err
+	}
+
+	// Work out what to do with each path first, so that a bad redirect or conflict doesn't leave a partial import
+	var creates, updates redirects
+	seen := make(map[string]bool)
+	for i, imported := range red {
+		imported.Id = 0
+		if !strings.HasPrefix(imported.Path, "/") {
+			imported.Path = "/" + imported.Path
+		}
+		if err = validateLink(imported); err != nil {
+			return report, fmt.Errorf("redirect %d: %v", i+1, err)
+		}
+		if err = validatePath(imported.Path); err != nil {
+			return report, fmt.Errorf("redirect %d: %v", i+1, err)
+		}
+
+		if seen[imported.Path] {
+			report.Duplicates = append(report.Duplicates, imported.Path)
+			continue
+		}
+		seen[imported.Path] = true
+
+		existing, err := store.Get(ctx, imported.Path)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			creates = append(creates, imported)
+			report.Created = append(report.Created, imported.Path)
+			continue
+		case err != nil:
+			return report, err
+		case existing.link().equal(imported.link()):
+			report.Unchanged = append(report.Unchanged, imported.Path)
+			continue
+		}
+
+		report.Conflicts = append(report.Conflicts, imported.Path)
+		switch merge {
+		case MergeOverwrite:
+			updates = append(updates, imported)
+			report.Updated = append(report.Updated, imported.Path)
+		case MergeSkip:
+			report.Skipped = append(report.Skipped, imported.Path)
+		}
+	}
+
+	if merge == MergeFail && len(report.Conflicts) > 0 {
+		return report, fmt.Errorf("%d path(s) already have a different redirect, so nothing was imported: %s",
+			len(report.Conflicts), strings.Join(report.Conflicts, ", "))
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	return report, writeBatch(ctx, store, creates, updates)
+}
+
+// encodeRedirects writes red to w in format.
+func encodeRedirects(w io.Writer, format string, red redirects) error {
+	var b []byte
+	var err error
+	switch format {
+	case FormatYAML:
+		b, err = yaml.Marshal(red)
+	case FormatJSON:
+		b, err = json.MarshalIndent(red, "", "  ")
+		b = append(b, '\n')
+	case FormatCSV:
+		return encodeCSV(w, red)
+	default:
+		return fmt.Errorf("unknown format '%s', expected one of: yaml, json, csv", format)
+	}
+	if err != nil {
+		return fmt.Errorf("marshalling: %v", err)
+	}
+
+	if _, err = w.Write(b); err != nil {
+		return fmt.Errorf("writing redirects: %v", err)
+	}
+
+	return nil
+}
+
+// decodeRedirects reads redirects in format from r.
+func decodeRedirects(r io.Reader, format string) (redirects, error) {
+	if format == FormatCSV {
+		return decodeCSV(r)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading redirects: %v", err)
+	}
+	switch format {
+	case FormatYAML:
+		return parseYAML(b)
+	case FormatJSON:
+		return parseJSON(b)
+	}
+
+	return nil, fmt.Errorf("unknown format '%s', expected one of: yaml, json, csv", format)
+}
+
+// encodeCSV writes red as CSV with a header row, leaving the options which aren't set empty.
+func encodeCSV(w io.Writer, red redirects) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return fmt.Errorf("writing redirects: %v", err)
+	}
+
+	for _, r := range red {
+		row := []string{r.Path, r.URL, "", "", "", "", ""}
+		if r.Status != 0 {
+			row[2] = strconv.Itoa(r.Status)
+		}
+		if r.ExpiresAt != nil {
+			row[3] = r.ExpiresAt.Format(time.RFC3339)
+		}
+		if r.Enabled != nil {
+			row[4] = strconv.FormatBool(*r.Enabled)
+		}
+		if r.Prefix {
+			row[5] = "true"
+		}
+		if r.PassQuery {
+			row[6] = "true"
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("writing redirects: %v", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing redirects: %v", err)
+	}
+
+	return nil
+}
+
+// decodeCSV reads redirects from CSV, using the header row to find the columns.
+func decodeCSV(r io.Reader) (redirects, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return redirects{}, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		known := false
+		for _, c := range csvColumns {
+			known = known || c == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown CSV column '%s', expected: %s", name, strings.Join(csvColumns, ", "))
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["path"]; !ok {
+		return nil, fmt.Errorf("the CSV header must have a path column")
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, fmt.Errorf("the CSV header must have a url column")
+	}
+
+	red := make(redirects, 0, len(rows)-1)
+	for n, row := range rows[1:] {
+		line := n + 2
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		rd := redirect{Path: cell("path"), URL: cell("url")}
+		if s := cell("status"); s != "" {
+			if rd.Status, err = strconv.Atoi(s); err != nil {
+				return nil, fmt.Errorf("line %d: invalid status '%s'", line, s)
+			}
+		}
+		if s := cell("expires_at"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid expires_at '%s', expected an RFC 3339 time", line, s)
+			}
+			rd.ExpiresAt = &t
+		}
+		if s := cell("enabled"); s != "" {
+			enabled, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid enabled '%s', expected true or false", line, s)
+			}
+			rd.Enabled = &enabled
+		}
+		for _, name := range []string{"prefix", "pass_query"} {
+			s := cell(name)
+			if s == "" {
+				continue
+			}
+			set, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s '%s', expected true or false", line, name, s)
+			}
+			if name == "prefix" {
+				rd.Prefix = set
+			} else {
+				rd.PassQuery = set
+			}
+		}
+		red = append(red, rd)
+	}
+
+	return red, red.validate()
+}
@@ -0,0 +1,175 @@
+package urlshort
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := NewFileStore(writeConfig(t, "config.yaml", `
+- path: /b
+  url: https://b.example
+  status: 302
+  expires_at: 2030-01-02T03:04:05Z
+  enabled: false
+- path: /a
+  url: https://a.example
+- path: /docs
+  url: https://docs.example
+  prefix: true
+  pass_query: true
+`))
+	expected, err := source.All(ctx)
+	assert.NoError(t, err)
+
+	for _, format := range []string{FormatYAML, FormatJSON, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := ExportRedirects(ctx, source, &buf, format)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, n)
+
+			target := openTestBoltStore(t)
+			report, err := ImportRedirects(ctx, target, &buf, format, MergeFail, false)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"/a", "/b", "/docs"}, report.Created, "expected the export to be ordered by path")
+
+			for _, r := range expected {
+				got, err := target.Get(ctx, r.Path)
+				assert.NoError(t, err)
+				assert.True(t, got.link().equal(r.link()), "expected %s to round trip, got %+v", r.Path, got)
+			}
+		})
+	}
+}
+
+func Test_ImportRedirectsMerge(t *testing.T) {
+	ctx := context.Background()
+	input := `path,url
+/same,https://same.example
+/changed,https://new.example
+/new,https://new.example/page
+/new,https://other.example
+`
+
+	tt := []struct {
+		name            string
+		merge           MergeStrategy
+		dryRun          bool
+		expectedErr     bool
+		expectedChanged string
+		expectedNew     bool
+		expectedReport  ImportReport
+	}{
+		{name: "fail", merge: MergeFail, expectedErr: true, expectedChanged: "https://old.example",
+			expectedReport: ImportReport{Created: []string{"/new"}, Unchanged: []string{"/same"}, Duplicates: []string{"/new"}, Conflicts: []string{"/changed"}}},
+		{name: "skip", merge: MergeSkip, expectedChanged: "https://old.example", expectedNew: true,
+			expectedReport: ImportReport{Created: []string{"/new"}, Unchanged: []string{"/same"}, Skipped: []string{"/changed"}, Duplicates: []string{"/new"}, Conflicts: []string{"/changed"}}},
+		{name: "overwrite", merge: MergeOverwrite, expectedChanged: "https://new.example", expectedNew: true,
+			expectedReport: ImportReport{Created: []string{"/new"}, Updated: []string{"/changed"}, Unchanged: []string{"/same"}, Duplicates: []string{"/new"}, Conflicts: []string{"/changed"}}},
+		{name: "dry run", merge: MergeOverwrite, dryRun: true, expectedChanged: "https://old.example",
+			expectedReport: ImportReport{Created: []string{"/new"}, Updated: []string{"/changed"}, Unchanged: []string{"/same"}, Duplicates: []string{"/new"}, Conflicts: []string{"/changed"}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewFileStore(writeConfig(t, "config.yaml", `
+- path: /same
+  url: https://same.example
+- path: /changed
+  url: https://old.example
+`))
+
+			report, err := ImportRedirects(ctx, store, strings.NewReader(input), FormatCSV, tc.merge, tc.dryRun)
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedReport, report)
+
+			changed, err := store.Get(ctx, "/changed")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedChanged, changed.URL)
+
+			created, err := store.Get(ctx, "/new")
+			if tc.expectedNew {
+				assert.NoError(t, err)
+				assert.Equal(t, "https://new.example/page", created.URL, "expected the first of the duplicates to be imported")
+			} else {
+				assert.ErrorIs(t, err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func Test_ImportRedirectsInvalid(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(writeConfig(t, "config.yaml", "[]"))
+
+	_, err := ImportRedirects(ctx, store, strings.NewReader("path,url,colour\n/a,https://a.example,red\n"), FormatCSV, MergeFail, false)
+	assert.Error(t, err, "expected an error for an unknown column")
+
+	_, err = ImportRedirects(ctx, store, strings.NewReader("path,url,status\n/a,https://a.example,200\n"), FormatCSV, MergeFail, false)
+	assert.Error(t, err, "expected an error for a status which isn't a redirect")
+
+	_, err = ImportRedirects(ctx, store, strings.NewReader(`[{"path": "/a", "url": "https://a.example"}, {"path": "/b", "url": "not a url"}]`), FormatJSON, MergeFail, false)
+	assert.Error(t, err, "expected an error for an invalid URL")
+
+	red, err := store.All(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(red), "expected nothing to be imported when any redirect is invalid")
+}
+
+func Test_WriteBatch(t *testing.T) {
+	ctx := context.Background()
+	config := "- path: /a\n  url: https://a.example\n"
+
+	tt := []struct {
+		name  string
+		store func(t *testing.T) Store
+	}{
+		{name: "file", store: func(t *testing.T) Store { return NewFileStore(writeConfig(t, "config.yaml", config)) }},
+		{name: "bolt", store: func(t *testing.T) Store {
+			store := openTestBoltStore(t)
+			_, err := store.Create(ctx, redirect{Path: "/a", URL: "https://a.example"})
+			assert.NoError(t, err)
+			return store
+		}},
+		{name: "chain", store: func(t *testing.T) Store {
+			return NewChain(
+				Source{Name: "builtin", Store: NewMapStore(map[string]string{"/builtin": "https://builtin.example"})},
+				Source{Name: "file", Store: NewFileStore(writeConfig(t, "config.yaml", config))},
+			)
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.store(t)
+			_, ok := store.(batchStore)
+			assert.True(t, ok, "expected the store to write batches")
+
+			err := writeBatch(ctx, store, redirects{{Path: "/b", URL: "https://b.example"}, {Path: "/a", URL: "https://other.example"}}, nil)
+			assert.ErrorIs(t, err, ErrExists)
+			err = writeBatch(ctx, store, redirects{{Path: "/b", URL: "https://b.example"}}, redirects{{Path: "/missing", URL: "https://missing.example"}})
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = store.Get(ctx, "/b")
+			assert.ErrorIs(t, err, ErrNotFound, "expected nothing to be written when any redirect fails")
+
+			err = writeBatch(ctx, store, redirects{{Path: "/b", URL: "https://b.example"}}, redirects{{Path: "/a", URL: "https://changed.example"}})
+			assert.NoError(t, err)
+			created, err := store.Get(ctx, "/b")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://b.example", created.URL)
+			updated, err := store.Get(ctx, "/a")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://changed.example", updated.URL)
+		})
+	}
+}